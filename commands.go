@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+
+	"github.com/coredao-org/btc-relayer/common"
+	config "github.com/coredao-org/btc-relayer/config"
+	"github.com/coredao-org/btc-relayer/executor"
+	"github.com/coredao-org/btc-relayer/model"
+	"github.com/coredao-org/btc-relayer/relayer"
+)
+
+// parse flags of a command, return the exit code if parsing failed
+func parseFlags(fs *flag.FlagSet, args []string) (bool, int) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return false, exitCodeOK
+		}
+		return false, exitCodeUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected arguments: %v\n", fs.Args())
+		fs.Usage()
+		return false, exitCodeUsage
+	}
+	return true, exitCodeOK
+}
+
+// parse flags of a command taking one argument, given before or after the flags
+func parseFlagsWithArg(fs *flag.FlagSet, args []string) (string, bool, int) {
+	var arg string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		arg, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return "", false, exitCodeOK
+		}
+		return "", false, exitCodeUsage
+	}
+	rest := fs.Args()
+	if arg == "" && len(rest) > 0 {
+		arg, rest = rest[0], rest[1:]
+	}
+	if len(rest) > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected arguments: %v\n", rest)
+		fs.Usage()
+		return "", false, exitCodeUsage
+	}
+	if arg == "" {
+		fmt.Fprintln(os.Stderr, "missing argument")
+		fs.Usage()
+		return "", false, exitCodeUsage
+	}
+	return arg, true, exitCodeOK
+}
+
+// init cfg
+func initCfg(configPath string) (*config.Config, error) {
+	if configPath == "" {
+		configPath = config.DefaultConfigPath()
+	}
+	return config.ParseConfigFromFile(configPath)
+}
+
+// read cfg for a command using the relay state store only, only the sections it needs are validated
+func initStoreCfg(configPath string) (*config.Config, error) {
+	if configPath == "" {
+		configPath = config.DefaultConfigPath()
+	}
+	cfg, err := config.ReadConfigFromFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.ValidateStore(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// init cfg, logger and executors
+func initRelayer(configPath string) (*relayer.Relayer, int) {
+	cfg, err := initCfg(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to get configuration: %s\n", err.Error())
+		return nil, exitCodeConfigError
+	}
+
+	//init logger
+	common.InitLogger(&cfg.LogConfig)
+
+	//init executors
+	btcExecutor, err := executor.NewBTCExecutor(cfg)
+	if err != nil {
+		common.Logger.Error(err.Error())
+		fmt.Fprintln(os.Stderr, err.Error())
+		return nil, exitCodeError
+	}
+
+	//init relay state store
+	db, err := model.OpenDB(&cfg.DBConfig)
+	if err != nil {
+		common.Logger.Error(err.Error())
+		fmt.Fprintln(os.Stderr, err.Error())
+		return nil, exitCodeError
+	}
+
+	coreExecutor, err := executor.NewCOREExecutor(db, cfg)
+	if err != nil {
+		common.Logger.Error(err.Error())
+		fmt.Fprintln(os.Stderr, err.Error())
+		return nil, exitCodeError
+	}
+
+	return relayer.NewRelayer(cfg, btcExecutor, coreExecutor), exitCodeOK
+}
+
+func runCmd(args []string) int {
+	fs, configPath := newFlagSet("run")
+	dryRun := fs.Bool("dry-run", false, "simulate the relay of new blocks with eth_call and log it, nothing is signed or sent")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	relayerInstance, code := initRelayer(*configPath)
+	if relayerInstance == nil {
+		return code
+	}
+	relayerInstance.SetDryRun(*dryRun)
+
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go func() {
+		sig := <-sigs
+		common.Logger.Infof("Received signal %s, shutting down", sig.String())
+		stop()
+		//a second signal does not wait for the shutdown
+		sig = <-sigs
+		common.Logger.Infof("Received signal %s again, exit now", sig.String())
+		os.Exit(exitCodeError)
+	}()
+
+	common.Logger.Info("Starting relayer")
+	if err := relayerInstance.Start(ctx); err != nil {
+		common.Logger.Error(err.Error())
+		fmt.Fprintln(os.Stderr, err.Error())
+		relayerInstance.Shutdown(context.Background())
+		return exitCodeError
+	}
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), relayerInstance.ShutdownTimeout())
+	defer cancel()
+	if err := relayerInstance.Shutdown(shutdownCtx); err != nil {
+		common.Logger.Error(err.Error())
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitCodeError
+	}
+	common.Logger.Info("Relayer stopped")
+	return exitCodeOK
+}
+
+func statusCmd(args []string) int {
+	fs, configPath := newFlagSet("status")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	relayerInstance, code := initRelayer(*configPath)
+	if relayerInstance == nil {
+		return code
+	}
+	defer relayerInstance.Shutdown(context.Background())
+
+	status, err := relayerInstance.GetStatus()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "query status error: %s\n", err.Error())
+		return exitCodeError
+	}
+
+	fmt.Printf("relayer address:      %s\n", status.RelayerAddress)
+	fmt.Printf("registered:           %t\n", status.Registered)
+	fmt.Printf("balance:              %s\n", status.Balance.String())
+	fmt.Printf("light client tip:     %s\n", status.ChainTip)
+	fmt.Printf("light client height:  %d\n", status.ChainTipHeight)
+	fmt.Printf("btc latest height:    %d\n", status.BTCLatestHeight)
+	return exitCodeOK
+}
+
+func registerCmd(args []string) int {
+	fs, configPath := newFlagSet("register")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	relayerInstance, code := initRelayer(*configPath)
+	if relayerInstance == nil {
+		return code
+	}
+	defer relayerInstance.Shutdown(context.Background())
+
+	if err := relayerInstance.RegisterRelayerHub(); err != nil {
+		fmt.Fprintf(os.Stderr, "register relayer error: %s\n", err.Error())
+		return exitCodeError
+	}
+	fmt.Println("relayer is registered")
+	return exitCodeOK
+}
+
+func unregisterCmd(args []string) int {
+	fs, configPath := newFlagSet("unregister")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	relayerInstance, code := initRelayer(*configPath)
+	if relayerInstance == nil {
+		return code
+	}
+	defer relayerInstance.Shutdown(context.Background())
+
+	if err := relayerInstance.UnregisterRelayerHub(); err != nil {
+		fmt.Fprintf(os.Stderr, "unregister relayer error: %s\n", err.Error())
+		return exitCodeError
+	}
+	fmt.Println("relayer is unregistered")
+	return exitCodeOK
+}
+
+func relayRangeCmd(args []string) int {
+	fs, configPath := newFlagSet("relay-range")
+	from := fs.Int64("from", -1, "first btc height to relay")
+	to := fs.Int64("to", -1, "last btc height to relay")
+	dryRun := fs.Bool("dry-run", false, "print what would be relayed, simulated with eth_call, nothing is signed or sent")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if *from < 0 || *to < *from {
+		fmt.Fprintln(os.Stderr, "--from and --to are required and --to should not be less than --from")
+		fs.Usage()
+		return exitCodeUsage
+	}
+
+	relayerInstance, code := initRelayer(*configPath)
+	if relayerInstance == nil {
+		return code
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if *dryRun {
+		return dryRunRange(ctx, relayerInstance, *from, *to)
+	}
+	results, err := relayerInstance.RelayRange(ctx, *from, *to)
+	relayerInstance.Shutdown(context.Background())
+	return printRelayResults(results, err)
+}
+
+func relayHashCmd(args []string) int {
+	fs, configPath := newFlagSet("relay-hash")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, "usage: ./btc-relayer relay-hash <blockhash> [flags]\n")
+		fs.PrintDefaults()
+	}
+	arg, ok, code := parseFlagsWithArg(fs, args)
+	if !ok {
+		return code
+	}
+
+	//NewHashFromStr accepts a short hash, a hash by hand must be complete
+	blockHash, err := chainhash.NewHashFromStr(arg)
+	if err != nil || len(arg) != 2*chainhash.HashSize {
+		fmt.Fprintf(os.Stderr, "invalid block hash %q, should be %d hex characters\n", arg, 2*chainhash.HashSize)
+		return exitCodeUsage
+	}
+
+	relayerInstance, code := initRelayer(*configPath)
+	if relayerInstance == nil {
+		return code
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	result, err := relayerInstance.RelayHash(ctx, blockHash)
+	relayerInstance.Shutdown(context.Background())
+	return printRelayResults([]*relayer.HeaderResult{result}, err)
+}
+
+func checkConfigCmd(args []string) int {
+	fs, configPath := newFlagSet("check-config")
+	printCfg := fs.Bool("print", false, "print the effective config, with env overrides applied and secrets redacted")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	cfg, err := initCfg(*configPath)
+	if *printCfg && cfg != nil {
+		bz, _ := json.MarshalIndent(cfg.Redacted(), "", "  ")
+		fmt.Println(string(bz))
+	}
+	if err == nil {
+		fmt.Println("configuration is valid")
+		return exitCodeOK
+	}
+
+	validationErrs, ok := err.(config.ValidationErrors)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "failed to get configuration: %s\n", err.Error())
+		return exitCodeConfigError
+	}
+	fmt.Printf("configuration is invalid, %d problem(s) found:\n", len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		fmt.Printf("  - %s\n", fieldErr.Error())
+	}
+	return exitCodeConfigError
+}
@@ -9,6 +9,23 @@ type Task struct {
 	Height    int64
 	BlockHash *chainhash.Hash
 	BLOCK     *wire.MsgBlock
+	// PARTS is set instead of BLOCK if only the parts of the block needed for relaying are fetched
+	PARTS *BlockParts
+}
+
+// Header is the header of the block, whether fetched in full or in parts
+func (task *Task) Header() *wire.BlockHeader {
+	if task.PARTS != nil {
+		return &task.PARTS.Header
+	}
+	return &task.BLOCK.Header
+}
+
+// BlockParts is what relaying needs of a block: the header, the coinbase tx and the txids
+type BlockParts struct {
+	Header   wire.BlockHeader
+	Coinbase *wire.MsgTx
+	TxHashes []chainhash.Hash
 }
 
 type TaskSet struct {
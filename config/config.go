@@ -10,6 +10,11 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/tyler-smith/go-bip39"
 )
 
 type Config struct {
@@ -18,53 +23,331 @@ type Config struct {
 	COREConfig       COREConfig       `json:"core_config"`
 	LogConfig        LogConfig        `json:"log_config"`
 	AlertConfig      AlertConfig      `json:"alert_config"`
+	DBConfig         DBConfig         `json:"db_config"`
 }
 
 type CrossChainConfig struct {
 	RecursionHeight int64 `json:"recursion_height"`
+	PipelineWindow  int64 `json:"pipeline_window"`
+	// ShutdownTimeoutSecond bounds the wait for the relay txs in flight on shutdown
+	ShutdownTimeoutSecond int64 `json:"shutdown_timeout_second"`
+}
+
+func (cfg *CrossChainConfig) Validate() error {
+	var errs ValidationErrors
+	if cfg.RecursionHeight <= 0 || cfg.RecursionHeight > MaxRecursionHeight {
+		errs.add("cross_chain_config.recursion_height", "should be in [1, %d], got %d", MaxRecursionHeight, cfg.RecursionHeight)
+	}
+	if cfg.PipelineWindow < 0 || cfg.PipelineWindow > MaxPipelineWindow {
+		errs.add("cross_chain_config.pipeline_window", "should be in [0, %d], got %d", MaxPipelineWindow, cfg.PipelineWindow)
+	}
+	if cfg.ShutdownTimeoutSecond < 0 {
+		errs.add("cross_chain_config.shutdown_timeout_second", "should not be negative, got %d", cfg.ShutdownTimeoutSecond)
+	}
+	return errs.errOrNil()
+}
+
+// GetPipelineWindow is the number of relay txs in flight during catch-up, 1 relays one height at a time
+func (cfg *CrossChainConfig) GetPipelineWindow() int64 {
+	if cfg.PipelineWindow == 0 {
+		return 1
+	}
+	return cfg.PipelineWindow
 }
 
-func (cfg *CrossChainConfig) Validate() {
+// GetShutdownTimeout is DefaultShutdownTimeoutSecond if not set
+func (cfg *CrossChainConfig) GetShutdownTimeout() time.Duration {
+	if cfg.ShutdownTimeoutSecond == 0 {
+		return DefaultShutdownTimeoutSecond * time.Second
+	}
+	return time.Duration(cfg.ShutdownTimeoutSecond) * time.Second
 }
 
 type BTCRpcAddrs struct {
-	Host string `json:"host"`
-	User string `json:"user"`
-	Pass string `json:"pass"`
+	// Source is bitcoind (default), esplora or p2p. For esplora Host is the url of the REST API, for p2p the address of a peer
+	Source string `json:"source"`
+	Host   string `json:"host"`
+	User   string `json:"user"`
+	Pass   string `json:"pass" secret:"true"`
+	// ZmqAddr is the zmqpubhashblock or zmqpubrawblock endpoint of the node, new blocks are pushed instead of polled if set
+	ZmqAddr string `json:"zmq_addr"`
+}
+
+// GetSource is the kind of BTC data source, bitcoind if not set
+func (cfg *BTCRpcAddrs) GetSource() string {
+	if cfg.Source == "" {
+		return BTCSourceBitcoind
+	}
+	return cfg.Source
 }
 
 type BTCConfig struct {
-	RpcAddrs                     []BTCRpcAddrs `json:"rpc_addrs"`
-	SleepSecond                  uint64        `json:"sleep_second"`
-	DataSeedDenyServiceThreshold float64       `json:"data_seed_deny_service_threshold"`
+	// Network is the BTC network of the rpc addresses, its consensus rules validate the headers, mainnet if not set
+	Network  string        `json:"network"`
+	RpcAddrs []BTCRpcAddrs `json:"rpc_addrs"`
+	// Quorum is the number of rpc addresses that must agree on the block hash of a height to relay it, 0 or 1 trusts the current one
+	Quorum                       int     `json:"quorum"`
+	SleepSecond                  uint64  `json:"sleep_second"`
+	DataSeedDenyServiceThreshold float64 `json:"data_seed_deny_service_threshold"`
 }
 
-func (cfg *BTCConfig) Validate() {
+// GetNetwork is the BTC network, mainnet if not set
+func (cfg *BTCConfig) GetNetwork() string {
+	if cfg.Network == "" {
+		return BTCNetworkMainnet
+	}
+	return cfg.Network
+}
+
+func (cfg *BTCConfig) Validate() error {
+	var errs ValidationErrors
 	if len(cfg.RpcAddrs) == 0 {
-		panic("rpc endpoint of BTC chain should not be empty")
+		errs.add("btc_config.rpc_addrs", "rpc endpoint of BTC chain should not be empty")
+	}
+	for i, rpcAddr := range cfg.RpcAddrs {
+		switch rpcAddr.GetSource() {
+		case BTCSourceBitcoind:
+			if err := checkRpcHost(rpcAddr.Host); err != nil {
+				errs.add(fmt.Sprintf("btc_config.rpc_addrs[%d].host", i), err.Error())
+			}
+		case BTCSourceEsplora:
+			if err := checkEsploraURL(rpcAddr.Host); err != nil {
+				errs.add(fmt.Sprintf("btc_config.rpc_addrs[%d].host", i), err.Error())
+			}
+		case BTCSourceP2P:
+			if err := checkPeerAddr(rpcAddr.Host); err != nil {
+				errs.add(fmt.Sprintf("btc_config.rpc_addrs[%d].host", i), err.Error())
+			}
+		default:
+			errs.add(fmt.Sprintf("btc_config.rpc_addrs[%d].source", i), "should be one of %s, %s, %s, got %q", BTCSourceBitcoind, BTCSourceEsplora, BTCSourceP2P, rpcAddr.Source)
+		}
+		if rpcAddr.ZmqAddr != "" {
+			if rpcAddr.GetSource() != BTCSourceBitcoind {
+				errs.add(fmt.Sprintf("btc_config.rpc_addrs[%d].zmq_addr", i), "is only supported by source %s", BTCSourceBitcoind)
+			} else if err := checkZmqAddr(rpcAddr.ZmqAddr); err != nil {
+				errs.add(fmt.Sprintf("btc_config.rpc_addrs[%d].zmq_addr", i), err.Error())
+			}
+		}
+	}
+	if cfg.Quorum < 0 || cfg.Quorum > len(cfg.RpcAddrs) {
+		errs.add("btc_config.quorum", "should be in [0, %d], the number of rpc addresses, got %d", len(cfg.RpcAddrs), cfg.Quorum)
+	}
+	switch cfg.GetNetwork() {
+	case BTCNetworkMainnet, BTCNetworkTestnet3, BTCNetworkSignet, BTCNetworkRegtest:
+	default:
+		errs.add("btc_config.network", "should be one of %s, %s, %s, %s, got %q", BTCNetworkMainnet, BTCNetworkTestnet3, BTCNetworkSignet, BTCNetworkRegtest, cfg.Network)
 	}
+	if cfg.SleepSecond == 0 {
+		errs.add("btc_config.sleep_second", "should be larger than 0")
+	}
+	if cfg.DataSeedDenyServiceThreshold < 0 {
+		errs.add("btc_config.data_seed_deny_service_threshold", "should not be negative")
+	}
+	return errs.errOrNil()
 }
 
 type COREConfig struct {
-	PrivateKey                   string   `json:"private_key"`
+	KeyType                      string   `json:"key_type"`
+	PrivateKey                   string   `json:"private_key" secret:"true"`
+	Mnemonic                     string   `json:"mnemonic" secret:"true"`
+	DerivationPath               string   `json:"derivation_path"`
+	KeystorePath                 string   `json:"keystore_path"`
+	KeystorePasswordFile         string   `json:"keystore_password_file"`
+	KeystorePasswordEnv          string   `json:"keystore_password_env"`
+	SecretName                   string   `json:"secret_name"`
+	AWSRegion                    string   `json:"aws_region"`
+	AWSEndpoint                  string   `json:"aws_endpoint"`
+	ExternalSigner               string   `json:"external_signer"`
+	SignerAddress                string   `json:"signer_address"`
 	Providers                    []string `json:"providers"`
 	GasLimit                     uint64   `json:"gas_limit"`
 	GasPrice                     uint64   `json:"gas_price"`
 	GasIncrease                  uint64   `json:"gas_increase"`
+	GasMarginPercent             uint64   `json:"gas_margin_percent"`
+	MaxGasLimit                  uint64   `json:"max_gas_limit"`
+	FeeMode                      string   `json:"fee_mode"`
+	MaxFeePerGas                 uint64   `json:"max_fee_per_gas"`
+	TipMultiplier                float64  `json:"tip_multiplier"`
+	ConfirmTimeoutSecond         uint64   `json:"confirm_timeout_second"`
+	Confirmations                uint64   `json:"confirmations"`
+	StuckTxBlocks                uint64   `json:"stuck_tx_blocks"`
+	DroppedTxBlocks              uint64   `json:"dropped_tx_blocks"`
+	GasPriceBumpPercent          uint64   `json:"gas_price_bump_percent"`
+	MaxGasPrice                  uint64   `json:"max_gas_price"`
 	SleepSecond                  uint64   `json:"sleep_second"`
 	DataSeedDenyServiceThreshold float64  `json:"data_seed_deny_service_threshold"`
 }
 
-func (cfg *COREConfig) Validate() {
+func (cfg *COREConfig) Validate() error {
+	var errs ValidationErrors
+	if cfg.ExternalSigner != "" {
+		cfg.validateExternalSigner(&errs)
+	} else {
+		cfg.validateKey(&errs)
+	}
+
 	if len(cfg.Providers) == 0 {
-		panic(fmt.Sprintf("provider address of Core Chain should not be empty"))
+		errs.add("core_config.providers", "provider address of Core Chain should not be empty")
+	}
+	for i, provider := range cfg.Providers {
+		if err := checkProviderURL(provider); err != nil {
+			errs.add(fmt.Sprintf("core_config.providers[%d]", i), err.Error())
+		}
 	}
 
 	if cfg.GasLimit == 0 {
-		panic(fmt.Sprintf("gas_limit of Core Chain should be larger than 0"))
+		errs.add("core_config.gas_limit", "gas_limit of Core Chain should be larger than 0")
+	}
+	if cfg.MaxGasLimit != 0 && cfg.MaxGasLimit < cfg.GasLimit {
+		errs.add("core_config.max_gas_limit", "should not be less than gas_limit")
+	}
+	switch cfg.FeeMode {
+	case "", FeeModeLegacy:
+	case FeeModeDynamic:
+		if cfg.MaxFeePerGas == 0 {
+			errs.add("core_config.max_fee_per_gas", "should be larger than 0 if fee_mode is %s", FeeModeDynamic)
+		}
+	default:
+		errs.add("core_config.fee_mode", "should be %s or %s, got %q", FeeModeLegacy, FeeModeDynamic, cfg.FeeMode)
+	}
+	if cfg.TipMultiplier < 0 {
+		errs.add("core_config.tip_multiplier", "should not be negative")
+	}
+	if cfg.GasPriceBumpPercent != 0 && cfg.GasPriceBumpPercent < MinGasPriceBumpPercent {
+		errs.add("core_config.gas_price_bump_percent", "should be at least %d, nodes reject smaller replacements", MinGasPriceBumpPercent)
+	}
+	if cfg.StuckTxBlocks > 0 && cfg.FeeMode != FeeModeDynamic && cfg.MaxGasPrice == 0 {
+		errs.add("core_config.max_gas_price", "should be larger than 0 if stuck_tx_blocks is set")
+	}
+	if cfg.SleepSecond == 0 {
+		errs.add("core_config.sleep_second", "should be larger than 0")
+	}
+	if cfg.DataSeedDenyServiceThreshold < 0 {
+		errs.add("core_config.data_seed_deny_service_threshold", "should not be negative")
+	}
+	return errs.errOrNil()
+}
+
+func (cfg *COREConfig) validateKey(errs *ValidationErrors) {
+	source, kind, err := ParseKeyType(cfg.KeyType)
+	if err != nil {
+		errs.add("core_config.key_type", err.Error())
+	}
+	if kind == KeyKindMnemonic {
+		if _, err := accounts.ParseDerivationPath(cfg.GetDerivationPath()); err != nil {
+			errs.add("core_config.derivation_path", err.Error())
+		}
+	}
+	if kind == KeyKindKeystore && cfg.KeystorePasswordFile == "" {
+		if _, ok := os.LookupEnv(cfg.GetKeystorePasswordEnv()); !ok {
+			errs.add("core_config.keystore_password_file", "keystore password should be given by keystore_password_file or env %s", cfg.GetKeystorePasswordEnv())
+		}
+	}
+	switch {
+	case source == LocalConfig && kind == KeyKindPrivateKey:
+		if err := checkPrivateKey(cfg.PrivateKey); err != nil {
+			errs.add("core_config.private_key", err.Error())
+		}
+	case source == LocalConfig && kind == KeyKindMnemonic:
+		if !bip39.IsMnemonicValid(cfg.Mnemonic) {
+			errs.add("core_config.mnemonic", "should be a valid BIP-39 mnemonic")
+		}
+	case source == LocalConfig && kind == KeyKindKeystore:
+		if cfg.KeystorePath == "" {
+			errs.add("core_config.keystore_path", "should not be empty if key_type is %s", cfg.KeyType)
+		}
+	case source != "":
+		if cfg.SecretName == "" {
+			errs.add("core_config.secret_name", "should not be empty if key_type is %s", cfg.KeyType)
+		}
+		if source == AWSConfig && cfg.AWSRegion == "" {
+			errs.add("core_config.aws_region", "should not be empty if key_type is %s", cfg.KeyType)
+		}
+		if cfg.AWSEndpoint != "" {
+			if err := checkProviderURL(cfg.AWSEndpoint); err != nil {
+				errs.add("core_config.aws_endpoint", err.Error())
+			}
+		}
 	}
 }
 
+func (cfg *COREConfig) validateExternalSigner(errs *ValidationErrors) {
+	if err := checkProviderURL(cfg.ExternalSigner); err != nil {
+		errs.add("core_config.external_signer", err.Error())
+	}
+	if !common.IsHexAddress(cfg.SignerAddress) {
+		errs.add("core_config.signer_address", "should be the hex address of the account held by external_signer")
+	}
+}
+
+func (cfg *COREConfig) GetDerivationPath() string {
+	if cfg.DerivationPath == "" {
+		return DefaultDerivationPath
+	}
+	return cfg.DerivationPath
+}
+
+func (cfg *COREConfig) GetKeystorePasswordEnv() string {
+	if cfg.KeystorePasswordEnv == "" {
+		return DefaultKeystorePasswordEnv
+	}
+	return cfg.KeystorePasswordEnv
+}
+
+func (cfg *COREConfig) GetGasMarginPercent() uint64 {
+	if cfg.GasMarginPercent == 0 {
+		return DefaultGasMarginPercent
+	}
+	return cfg.GasMarginPercent
+}
+
+// GetMaxGasLimit caps estimated gas and gas increases, gas_limit if max_gas_limit is not set
+func (cfg *COREConfig) GetMaxGasLimit() uint64 {
+	if cfg.MaxGasLimit == 0 {
+		return cfg.GasLimit
+	}
+	return cfg.MaxGasLimit
+}
+
+func (cfg *COREConfig) GetTipMultiplier() float64 {
+	if cfg.TipMultiplier == 0 {
+		return DefaultTipMultiplier
+	}
+	return cfg.TipMultiplier
+}
+
+func (cfg *COREConfig) GetGasPriceBumpPercent() uint64 {
+	if cfg.GasPriceBumpPercent == 0 {
+		return MinGasPriceBumpPercent
+	}
+	return cfg.GasPriceBumpPercent
+}
+
+// GetConfirmTimeout bounds the wait for the receipt of a relay transaction and its confirmations
+func (cfg *COREConfig) GetConfirmTimeout() time.Duration {
+	if cfg.ConfirmTimeoutSecond == 0 {
+		return DefaultConfirmTimeoutSecond * time.Second
+	}
+	return time.Duration(cfg.ConfirmTimeoutSecond) * time.Second
+}
+
+// GetConfirmations is the number of blocks including the one of the receipt, 1 if not set
+func (cfg *COREConfig) GetConfirmations() uint64 {
+	if cfg.Confirmations == 0 {
+		return DefaultConfirmations
+	}
+	return cfg.Confirmations
+}
+
+// GetDroppedTxBlocks is how many Core blocks a relay transaction may be unknown to the provider before it
+// is considered dropped, a load balanced provider may not know a pending tx for a while
+func (cfg *COREConfig) GetDroppedTxBlocks() uint64 {
+	if cfg.DroppedTxBlocks == 0 {
+		return DefaultDroppedTxBlocks
+	}
+	return cfg.DroppedTxBlocks
+}
+
 type LogConfig struct {
 	Level                        string `json:"level"`
 	Filename                     string `json:"filename"`
@@ -76,18 +359,20 @@ type LogConfig struct {
 	Compress                     bool   `json:"compress"`
 }
 
-func (cfg *LogConfig) Validate() {
+func (cfg *LogConfig) Validate() error {
+	var errs ValidationErrors
 	if cfg.UseFileLogger {
 		if cfg.Filename == "" {
-			panic("filename should not be empty if using file logger")
+			errs.add("log_config.filename", "filename should not be empty if using file logger")
 		}
 		if cfg.MaxFileSizeInMB <= 0 {
-			panic("max_file_size_in_mb should be larger than 0 if using file logger")
+			errs.add("log_config.max_file_size_in_mb", "max_file_size_in_mb should be larger than 0 if using file logger")
 		}
 		if cfg.MaxBackupsOfLogFiles <= 0 {
-			panic("max_backups_off_log_files should be larger than 0 if using file logger")
+			errs.add("log_config.max_backups_of_log_files", "max_backups_of_log_files should be larger than 0 if using file logger")
 		}
 	}
+	return errs.errOrNil()
 }
 
 type AlertConfig struct {
@@ -96,92 +381,156 @@ type AlertConfig struct {
 	Interval        int64 `json:"interval"`
 
 	Identity       string `json:"identity"`
-	TelegramBotId  string `json:"telegram_bot_id"`
+	TelegramBotId  string `json:"telegram_bot_id" secret:"true"`
 	TelegramChatId string `json:"telegram_chat_id"`
 
 	BalanceThreshold     string `json:"balance_threshold"`
 	SequenceGapThreshold uint64 `json:"sequence_gap_threshold"`
 }
 
-func (cfg *AlertConfig) Validate() {
+func (cfg *AlertConfig) Validate() error {
+	var errs ValidationErrors
 	if !cfg.EnableAlert {
-		return
+		return nil
 	}
 	if cfg.Interval <= 0 {
-		panic("alert interval should be positive")
+		errs.add("alert_config.interval", "alert interval should be positive")
 	}
 	balanceThreshold, ok := big.NewInt(1).SetString(cfg.BalanceThreshold, 10)
 	if !ok {
-		panic(fmt.Sprintf("unrecognized balance_threshold"))
+		errs.add("alert_config.balance_threshold", "unrecognized balance_threshold %q", cfg.BalanceThreshold)
+	} else if balanceThreshold.Cmp(big.NewInt(0)) <= 0 {
+		errs.add("alert_config.balance_threshold", "balance_threshold should be positive")
 	}
 
-	if balanceThreshold.Cmp(big.NewInt(0)) <= 0 {
-		panic(fmt.Sprintf("balance_threshold should be positive"))
+	if cfg.SequenceGapThreshold <= 0 {
+		errs.add("alert_config.sequence_gap_threshold", "sequence_gap_threshold should be positive")
 	}
+	return errs.errOrNil()
+}
 
-	if cfg.SequenceGapThreshold <= 0 {
-		panic(fmt.Sprintf("sequence_gap_threshold should be positive"))
+type DBConfig struct {
+	// Dialect is sqlite3 (default) or mysql
+	Dialect string `json:"dialect"`
+	// DBPath is the file of sqlite3, or the DSN of mysql, e.g. user:pass@tcp(127.0.0.1:3306)/btc_relayer?parseTime=true
+	DBPath string `json:"db_path" secret:"true"`
+}
+
+func (cfg *DBConfig) GetDialect() string {
+	if cfg.Dialect == "" {
+		return DBDialectSqlite3
 	}
+	return cfg.Dialect
 }
 
-func (cfg *Config) Validate() {
-	cfg.CrossChainConfig.Validate()
-	cfg.LogConfig.Validate()
-	cfg.BTCConfig.Validate()
-	cfg.COREConfig.Validate()
-	cfg.AlertConfig.Validate()
-	//cfg.DBConfig.Validate()
+// GetDBPath is DefaultSqlitePath in the working directory if not set for sqlite3
+func (cfg *DBConfig) GetDBPath() string {
+	if cfg.DBPath == "" && cfg.GetDialect() == DBDialectSqlite3 {
+		return DefaultSqlitePath
+	}
+	return cfg.DBPath
 }
 
-func ParseConfigFromJson(content string) *Config {
-	var config Config
-	if err := json.Unmarshal([]byte(content), &config); err != nil {
-		panic(err)
+func (cfg *DBConfig) Validate() error {
+	var errs ValidationErrors
+	switch cfg.GetDialect() {
+	case DBDialectSqlite3:
+	case DBDialectMysql:
+		if cfg.DBPath == "" {
+			errs.add("db_config.db_path", "should not be empty if dialect is %s", DBDialectMysql)
+		}
+	default:
+		errs.add("db_config.dialect", "should be %s or %s, got %q", DBDialectSqlite3, DBDialectMysql, cfg.Dialect)
 	}
-	return &config
+	return errs.errOrNil()
+}
+
+// validate all sections, every problem found is returned in ValidationErrors
+func (cfg *Config) Validate() error {
+	var errs ValidationErrors
+	errs.merge(cfg.CrossChainConfig.Validate())
+	errs.merge(cfg.LogConfig.Validate())
+	errs.merge(cfg.BTCConfig.Validate())
+	errs.merge(cfg.COREConfig.Validate())
+	errs.merge(cfg.AlertConfig.Validate())
+	errs.merge(cfg.DBConfig.Validate())
+	return errs.errOrNil()
 }
 
-func ParseConfigFromFile(filePath string) *Config {
+// validate the sections of a command reading the relay state store only, it connects to no node
+func (cfg *Config) ValidateStore() error {
+	var errs ValidationErrors
+	errs.merge(cfg.LogConfig.Validate())
+	errs.merge(cfg.DBConfig.Validate())
+	return errs.errOrNil()
+}
 
-	fullFilePath := path.Join(GetCurrentAbPath(), string(os.PathSeparator)+filePath)
+// ParseConfigFromJson parses the config json, without the environment overrides or validation
+func ParseConfigFromJson(content string) (*Config, error) {
+	var config Config
+	if err := json.Unmarshal([]byte(content), &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
 
-	fmt.Println("config path:" + fullFilePath)
-	//fullFilePath := dir + string(os.PathSeparator) + filePath
-	bz, err := ioutil.ReadFile(fullFilePath)
+// ParseConfigFromFile reads the config file and validates all sections
+func ParseConfigFromFile(filePath string) (*Config, error) {
+	config, err := ReadConfigFromFile(filePath)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return config, err
 	}
+	return config, nil
+}
 
-	var config Config
+// ReadConfigFromFile reads the config file with the environment overrides, without validating it
+func ReadConfigFromFile(filePath string) (*Config, error) {
+	fmt.Fprintln(os.Stderr, "config path:"+filePath)
+	bz, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := json.Unmarshal(bz, &config); err != nil {
-		panic(err)
+	config, err := ParseConfigFromJson(string(bz))
+	if err != nil {
+		return nil, fmt.Errorf("parse config %s error: %w", filePath, err)
 	}
 
-	config.Validate()
+	// environment variables take precedence over the json file
+	if err := ApplyEnvOverrides(config, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
 
-	return &config
+// DefaultConfigPath is the config file used when --config-path is not given,
+// config/config.json next to the executable
+func DefaultConfigPath() string {
+	return path.Join(GetCurrentAbPath(), string(os.PathSeparator)+DefaultConfigFile)
 }
 
 //getCurrentAbPath
 func GetCurrentAbPath() string {
-	dir := getCurrentAbPathByExecutable()
+	dir, err := getCurrentAbPathByExecutable()
 	tmpDir, _ := filepath.EvalSymlinks(os.TempDir())
-	if strings.Contains(dir, tmpDir) {
+	if err != nil || strings.Contains(dir, tmpDir) {
 		return getCurrentAbPathByCaller()
 	}
 	return dir
 }
 
 // go build path
-func getCurrentAbPathByExecutable() string {
+func getCurrentAbPathByExecutable() (string, error) {
 	exePath, err := os.Executable()
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 	res, _ := filepath.EvalSymlinks(filepath.Dir(exePath))
 	res = res + string(os.PathSeparator) + "config"
-	return res
+	return res, nil
 }
 
 // go run path
@@ -0,0 +1,173 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func validConfig() *Config {
+	return &Config{
+		CrossChainConfig: CrossChainConfig{RecursionHeight: 10},
+		BTCConfig: BTCConfig{
+			RpcAddrs: []BTCRpcAddrs{
+				{Host: "127.0.0.1:8332", User: "user", Pass: "pass", ZmqAddr: "tcp://127.0.0.1:28332"},
+				{Source: BTCSourceEsplora, Host: "https://blockstream.info/api"},
+			},
+			SleepSecond: 1,
+		},
+		COREConfig: COREConfig{
+			PrivateKey:  "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318",
+			Providers:   []string{"https://rpc.coredao.org"},
+			GasLimit:    4700000,
+			SleepSecond: 1,
+		},
+	}
+}
+
+func fieldsOf(err error) []string {
+	fields := make([]string, 0)
+	for _, fieldErr := range err.(ValidationErrors) {
+		fields = append(fields, fieldErr.Field)
+	}
+	return fields
+}
+
+func TestConfig_Validate(t *testing.T) {
+	require.NoError(t, validConfig().Validate())
+
+	cfg := validConfig()
+	cfg.BTCConfig.Network = BTCNetworkRegtest
+	cfg.BTCConfig.RpcAddrs = append(cfg.BTCConfig.RpcAddrs, BTCRpcAddrs{Source: BTCSourceP2P, Host: "127.0.0.1:18444"})
+	cfg.BTCConfig.Quorum = 2
+	require.NoError(t, cfg.Validate())
+}
+
+func TestConfig_ValidateReportsAllProblems(t *testing.T) {
+	cfg := validConfig()
+	cfg.CrossChainConfig.RecursionHeight = MaxRecursionHeight + 1
+	cfg.CrossChainConfig.PipelineWindow = -1
+	cfg.CrossChainConfig.ShutdownTimeoutSecond = -1
+	cfg.BTCConfig.RpcAddrs = append(cfg.BTCConfig.RpcAddrs, BTCRpcAddrs{Host: "http://127.0.0.1:8332"}, BTCRpcAddrs{Host: "127.0.0.1:99999", ZmqAddr: "127.0.0.1:28332"},
+		BTCRpcAddrs{Source: BTCSourceEsplora, Host: "blockstream.info/api", ZmqAddr: "tcp://127.0.0.1:28332"}, BTCRpcAddrs{Source: "electrum", Host: "127.0.0.1:50001"},
+		BTCRpcAddrs{Source: BTCSourceP2P, Host: "127.0.0.1"})
+	cfg.BTCConfig.Quorum = 8
+	cfg.BTCConfig.Network = "testnet"
+	cfg.BTCConfig.SleepSecond = 0
+	cfg.COREConfig.PrivateKey = "not a key"
+	cfg.COREConfig.Providers = []string{"rpc.coredao.org", "wss://"}
+	cfg.COREConfig.SleepSecond = 0
+	cfg.AlertConfig = AlertConfig{EnableAlert: true, Interval: 300, BalanceThreshold: "abc"}
+	cfg.DBConfig = DBConfig{Dialect: DBDialectMysql}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Equal(t, []string{
+		"cross_chain_config.recursion_height",
+		"cross_chain_config.pipeline_window",
+		"cross_chain_config.shutdown_timeout_second",
+		"btc_config.rpc_addrs[2].host",
+		"btc_config.rpc_addrs[3].host",
+		"btc_config.rpc_addrs[3].zmq_addr",
+		"btc_config.rpc_addrs[4].host",
+		"btc_config.rpc_addrs[4].zmq_addr",
+		"btc_config.rpc_addrs[5].source",
+		"btc_config.rpc_addrs[6].host",
+		"btc_config.quorum",
+		"btc_config.network",
+		"btc_config.sleep_second",
+		"core_config.private_key",
+		"core_config.providers[0]",
+		"core_config.providers[1]",
+		"core_config.sleep_second",
+		"alert_config.balance_threshold",
+		"alert_config.sequence_gap_threshold",
+		"db_config.db_path",
+	}, fieldsOf(err))
+}
+
+func TestParseConfigFromFile(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "relayer.json")
+	require.NoError(t, os.WriteFile(filePath, []byte(`{"btc_config":{"rpc_addrs":[]}}`), 0600))
+
+	cfg, err := ParseConfigFromFile(filePath)
+	require.NotNil(t, cfg)
+	require.Contains(t, fieldsOf(err), "btc_config.rpc_addrs")
+
+	_, err = ParseConfigFromFile(filepath.Join(dir, "missing.json"))
+	require.Error(t, err)
+
+	//malformed json is an error, not a panic
+	require.NoError(t, os.WriteFile(filePath, []byte(`{"btc_config":`), 0600))
+	_, err = ParseConfigFromFile(filePath)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "parse config")
+	_, err = ParseConfigFromJson(`{"btc_config":`)
+	require.Error(t, err)
+}
+
+func TestConfig_ValidateStore(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "relayer.json")
+	require.NoError(t, os.WriteFile(filePath, []byte(`{"btc_config":{"rpc_addrs":[]},"db_config":{"dialect":"mysql"}}`), 0600))
+
+	//the sections of the nodes are not validated
+	cfg, err := ReadConfigFromFile(filePath)
+	require.NoError(t, err)
+	require.Equal(t, []string{"db_config.db_path"}, fieldsOf(cfg.ValidateStore()))
+
+	cfg.DBConfig.DBPath = "user:pass@tcp(127.0.0.1:3306)/btc_relayer"
+	require.NoError(t, cfg.ValidateStore())
+	cfg.LogConfig.UseFileLogger = true
+	require.Contains(t, fieldsOf(cfg.ValidateStore()), "log_config.filename")
+}
+
+func TestCOREConfig_ValidateKeyType(t *testing.T) {
+	cfg := validConfig()
+	cfg.COREConfig.KeyType = KeyTypeMnemonic
+	cfg.COREConfig.Mnemonic = "test test test test test test test test test test test junk"
+	require.NoError(t, cfg.Validate())
+
+	cfg.COREConfig.DerivationPath = "m/44'/60'/x"
+	cfg.COREConfig.Mnemonic = "test test test"
+	require.Equal(t, []string{"core_config.derivation_path", "core_config.mnemonic"}, fieldsOf(cfg.Validate()))
+
+	cfg.COREConfig.KeyType = KeyTypeAWSPrivateKey
+	require.Equal(t, []string{"core_config.secret_name", "core_config.aws_region"}, fieldsOf(cfg.Validate()))
+
+	cfg.COREConfig.KeyType = "ledger_private_key"
+	require.Equal(t, []string{"core_config.key_type"}, fieldsOf(cfg.Validate()))
+}
+
+func TestCOREConfig_ValidateExternalSigner(t *testing.T) {
+	cfg := validConfig()
+	cfg.COREConfig.PrivateKey = ""
+	cfg.COREConfig.ExternalSigner = "http://127.0.0.1:8550"
+	cfg.COREConfig.SignerAddress = "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	require.NoError(t, cfg.Validate())
+
+	cfg.COREConfig.ExternalSigner = "127.0.0.1:8550"
+	cfg.COREConfig.SignerAddress = ""
+	require.Equal(t, []string{"core_config.external_signer", "core_config.signer_address"}, fieldsOf(cfg.Validate()))
+}
+
+func TestCOREConfig_ValidateReplaceByFee(t *testing.T) {
+	cfg := validConfig()
+	cfg.COREConfig.StuckTxBlocks = 10
+	cfg.COREConfig.GasPriceBumpPercent = 5
+	require.Equal(t, []string{"core_config.gas_price_bump_percent", "core_config.max_gas_price"}, fieldsOf(cfg.Validate()))
+
+	cfg.COREConfig.GasPriceBumpPercent = 0
+	cfg.COREConfig.MaxGasPrice = 10000000000
+	require.NoError(t, cfg.Validate())
+	require.Equal(t, uint64(MinGasPriceBumpPercent), cfg.COREConfig.GetGasPriceBumpPercent())
+
+	// dynamic fee mode is capped by max_fee_per_gas
+	cfg.COREConfig.MaxGasPrice = 0
+	cfg.COREConfig.FeeMode = FeeModeDynamic
+	cfg.COREConfig.MaxFeePerGas = 10000000000
+	require.NoError(t, cfg.Validate())
+}
@@ -10,4 +10,52 @@ const (
 
 	KeyTypeMnemonic    = "local_mnemonic"
 	KeyTypeAWSMnemonic = "aws_mnemonic"
+
+	KeyKindPrivateKey = "private_key"
+	KeyKindMnemonic   = "mnemonic"
+	KeyKindKeystore   = "keystore"
+
+	KeyTypeLocalKeystore = "local_keystore"
+
+	// DefaultKeystorePasswordEnv holds the keystore password if keystore_password_file is not set
+	DefaultKeystorePasswordEnv = "BTC_RELAYER_KEYSTORE_PASSWORD"
+
+	DefaultDerivationPath = "m/44'/60'/0'/0/0"
+
+	DefaultConfigFile = "config.json"
+
+	// DefaultSqlitePath is the sqlite3 file of the relay state store
+	DefaultSqlitePath = "btc-relayer.db"
+
+	BTCSourceBitcoind = "bitcoind"
+	BTCSourceEsplora  = "esplora"
+	BTCSourceP2P      = "p2p"
+
+	BTCNetworkMainnet  = "mainnet"
+	BTCNetworkTestnet3 = "testnet3"
+	BTCNetworkSignet   = "signet"
+	BTCNetworkRegtest  = "regtest"
+
+	FeeModeLegacy  = "legacy"
+	FeeModeDynamic = "dynamic"
+
+	DefaultTipMultiplier = 1.0
+
+	DefaultGasMarginPercent = 20
+
+	// the price bump geth requires to replace a pending tx
+	MinGasPriceBumpPercent = 10
+
+	DefaultConfirmTimeoutSecond = 120
+	DefaultConfirmations        = 1
+	// Core blocks a relay tx may be unknown to the provider before it is considered dropped
+	DefaultDroppedTxBlocks = 5
+
+	// one difficulty adjustment interval
+	MaxRecursionHeight = 2016
+
+	MaxPipelineWindow = 64
+
+	// how long a relay tx in flight is waited for on SIGINT or SIGTERM
+	DefaultShutdownTimeoutSecond = 30
 )
@@ -0,0 +1,219 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EnvPrefix is prepended to the json path of a setting to build its environment variable,
+	// e.g. core_config.private_key is overridden by BTC_RELAYER_CORE_CONFIG_PRIVATE_KEY
+	EnvPrefix = "BTC_RELAYER"
+	// EnvFileSuffix marks a variable holding the path of a file to read the value from,
+	// e.g. BTC_RELAYER_CORE_CONFIG_PRIVATE_KEY_FILE=/run/secrets/relayer_key
+	EnvFileSuffix = "_FILE"
+
+	redactedValue = "******"
+)
+
+// LookupEnvFunc has the signature of os.LookupEnv
+type LookupEnvFunc func(key string) (string, bool)
+
+// EnvName returns the environment variable overriding the setting at the given json path
+func EnvName(jsonPath ...string) string {
+	return strings.ToUpper(strings.Join(append([]string{EnvPrefix}, jsonPath...), "_"))
+}
+
+// ApplyEnvOverrides overrides every field of cfg that has a matching environment variable.
+//
+// Scalars are set from NAME or, if NAME is not set, from the content of the file at NAME_FILE.
+// Slices can be replaced as a whole with NAME, given as a json array or, for string slices,
+// a comma separated list, and single elements can be set with NAME_<index>, e.g.
+// BTC_RELAYER_BTC_CONFIG_RPC_ADDRS_0_PASS. An index equal to the slice length appends an element.
+func ApplyEnvOverrides(cfg *Config, lookup LookupEnvFunc) error {
+	var errs ValidationErrors
+	applyEnv(reflect.ValueOf(cfg).Elem(), EnvPrefix, lookup, &errs)
+	return errs.errOrNil()
+}
+
+func applyEnv(v reflect.Value, name string, lookup LookupEnvFunc, errs *ValidationErrors) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			tag := jsonName(t.Field(i))
+			if tag == "" {
+				continue
+			}
+			applyEnv(v.Field(i), name+"_"+strings.ToUpper(tag), lookup, errs)
+		}
+	case reflect.Slice:
+		if value, ok, err := lookupValue(name, lookup); err != nil {
+			errs.add(name, err.Error())
+		} else if ok {
+			if err := setSlice(v, value); err != nil {
+				errs.add(name, err.Error())
+			}
+		}
+		for i := 0; ; i++ {
+			elemName := fmt.Sprintf("%s_%d", name, i)
+			if i >= v.Len() {
+				if !hasEnv(v.Type().Elem(), elemName, lookup) {
+					return
+				}
+				v.Set(reflect.Append(v, reflect.New(v.Type().Elem()).Elem()))
+			}
+			applyEnv(v.Index(i), elemName, lookup, errs)
+		}
+	default:
+		value, ok, err := lookupValue(name, lookup)
+		if err != nil {
+			errs.add(name, err.Error())
+			return
+		}
+		if !ok {
+			return
+		}
+		if err := setScalar(v, value); err != nil {
+			errs.add(name, err.Error())
+		}
+	}
+}
+
+// lookupValue reads NAME, falling back to the content of the file named by NAME_FILE
+func lookupValue(name string, lookup LookupEnvFunc) (string, bool, error) {
+	if value, ok := lookup(name); ok {
+		return value, true, nil
+	}
+	filePath, ok := lookup(name + EnvFileSuffix)
+	if !ok {
+		return "", false, nil
+	}
+	bz, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return "", false, fmt.Errorf("read %s error: %s", name+EnvFileSuffix, err.Error())
+	}
+	return strings.TrimRight(string(bz), "\r\n"), true, nil
+}
+
+// hasEnv reports whether any setting below name is given in the environment
+func hasEnv(t reflect.Type, name string, lookup LookupEnvFunc) bool {
+	if t.Kind() == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			tag := jsonName(t.Field(i))
+			if tag != "" && hasEnv(t.Field(i).Type, name+"_"+strings.ToUpper(tag), lookup) {
+				return true
+			}
+		}
+		return false
+	}
+	if _, ok := lookup(name); ok {
+		return true
+	}
+	_, ok := lookup(name + EnvFileSuffix)
+	return ok
+}
+
+func setSlice(v reflect.Value, value string) error {
+	trimmed := strings.TrimSpace(value)
+	if strings.HasPrefix(trimmed, "[") {
+		slice := reflect.New(v.Type())
+		if err := json.Unmarshal([]byte(trimmed), slice.Interface()); err != nil {
+			return fmt.Errorf("invalid json array: %s", err.Error())
+		}
+		v.Set(slice.Elem())
+		return nil
+	}
+	if v.Type().Elem().Kind() == reflect.Struct {
+		return fmt.Errorf("should be a json array")
+	}
+
+	slice := reflect.MakeSlice(v.Type(), 0, 0)
+	if trimmed != "" {
+		for _, item := range strings.Split(trimmed, ",") {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setScalar(elem, strings.TrimSpace(item)); err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, elem)
+		}
+	}
+	v.Set(slice)
+	return nil
+}
+
+func setScalar(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a bool", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not an unsigned integer", value)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func jsonName(field reflect.StructField) string {
+	tag := strings.Split(field.Tag.Get("json"), ",")[0]
+	if tag == "-" || field.PkgPath != "" {
+		return ""
+	}
+	return tag
+}
+
+// Redacted returns a copy of the config with every field tagged `secret:"true"` masked
+func (cfg *Config) Redacted() *Config {
+	var redacted Config
+	bz, _ := json.Marshal(cfg)
+	_ = json.Unmarshal(bz, &redacted)
+	redact(reflect.ValueOf(&redacted).Elem())
+	return &redacted
+}
+
+func redact(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := v.Field(i)
+			if t.Field(i).Tag.Get("secret") == "true" && field.Kind() == reflect.String {
+				if field.String() != "" {
+					field.SetString(redactedValue)
+				}
+				continue
+			}
+			redact(field)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			redact(v.Index(i))
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func lookupFrom(env map[string]string) LookupEnvFunc {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestApplyEnvOverrides(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "btc_pass")
+	require.NoError(t, os.WriteFile(secretFile, []byte("secret-pass\n"), 0600))
+
+	cfg := validConfig()
+	err := ApplyEnvOverrides(cfg, lookupFrom(map[string]string{
+		"BTC_RELAYER_CORE_CONFIG_PRIVATE_KEY":             "0xabc",
+		"BTC_RELAYER_CORE_CONFIG_PROVIDERS":               "http://127.0.0.1:8545, ws://127.0.0.1:8546",
+		"BTC_RELAYER_CORE_CONFIG_GAS_LIMIT":               "5000000",
+		"BTC_RELAYER_BTC_CONFIG_RPC_ADDRS_0_PASS_FILE":    secretFile,
+		"BTC_RELAYER_BTC_CONFIG_RPC_ADDRS_1_HOST":         "10.0.0.1:8332",
+		"BTC_RELAYER_LOG_CONFIG_USE_CONSOLE_LOGGER":       "true",
+		"BTC_RELAYER_CROSS_CHAIN_CONFIG_RECURSION_HEIGHT": "20",
+	}))
+	require.NoError(t, err)
+
+	require.Equal(t, "0xabc", cfg.COREConfig.PrivateKey)
+	require.Equal(t, []string{"http://127.0.0.1:8545", "ws://127.0.0.1:8546"}, cfg.COREConfig.Providers)
+	require.Equal(t, uint64(5000000), cfg.COREConfig.GasLimit)
+	require.Equal(t, "secret-pass", cfg.BTCConfig.RpcAddrs[0].Pass)
+	require.Equal(t, "127.0.0.1:8332", cfg.BTCConfig.RpcAddrs[0].Host)
+	require.Len(t, cfg.BTCConfig.RpcAddrs, 2)
+	require.Equal(t, "10.0.0.1:8332", cfg.BTCConfig.RpcAddrs[1].Host)
+	require.True(t, cfg.LogConfig.UseConsoleLogger)
+	require.Equal(t, int64(20), cfg.CrossChainConfig.RecursionHeight)
+}
+
+func TestApplyEnvOverrides_JsonSliceAndErrors(t *testing.T) {
+	cfg := validConfig()
+	err := ApplyEnvOverrides(cfg, lookupFrom(map[string]string{
+		"BTC_RELAYER_BTC_CONFIG_RPC_ADDRS": `[{"host":"a:1"},{"host":"b:2"}]`,
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []BTCRpcAddrs{{Host: "a:1"}, {Host: "b:2"}}, cfg.BTCConfig.RpcAddrs)
+
+	err = ApplyEnvOverrides(cfg, lookupFrom(map[string]string{
+		"BTC_RELAYER_CORE_CONFIG_GAS_LIMIT":        "lots",
+		"BTC_RELAYER_CORE_CONFIG_PRIVATE_KEY_FILE": filepath.Join(t.TempDir(), "missing"),
+	}))
+	require.Equal(t, []string{"BTC_RELAYER_CORE_CONFIG_PRIVATE_KEY", "BTC_RELAYER_CORE_CONFIG_GAS_LIMIT"}, fieldsOf(err))
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg := validConfig()
+	cfg.AlertConfig.TelegramBotId = "bot"
+
+	redacted := cfg.Redacted()
+	require.Equal(t, redactedValue, redacted.COREConfig.PrivateKey)
+	require.Equal(t, redactedValue, redacted.BTCConfig.RpcAddrs[0].Pass)
+	require.Equal(t, redactedValue, redacted.AlertConfig.TelegramBotId)
+	require.Equal(t, cfg.BTCConfig.RpcAddrs[0].User, redacted.BTCConfig.RpcAddrs[0].User)
+	require.Equal(t, "pass", cfg.BTCConfig.RpcAddrs[0].Pass)
+	require.Equal(t, "BTC_RELAYER_CORE_CONFIG_PRIVATE_KEY", EnvName("core_config", "private_key"))
+}
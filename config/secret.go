@@ -0,0 +1,79 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SecretProvider fetches a named secret from an external secret store
+type SecretProvider interface {
+	GetSecret(name string) (string, error)
+}
+
+// SecretProviderFactory builds a SecretProvider from the core_config settings
+type SecretProviderFactory func(cfg *COREConfig) (SecretProvider, error)
+
+var (
+	secretProvidersMutex sync.RWMutex
+	secretProviders      = map[string]SecretProviderFactory{
+		AWSConfig: newAWSSecretProvider,
+	}
+)
+
+// RegisterSecretProvider makes a secret provider available to key_type as <name>_private_key,
+// <name>_mnemonic and <name>_keystore
+func RegisterSecretProvider(name string, factory SecretProviderFactory) {
+	secretProvidersMutex.Lock()
+	defer secretProvidersMutex.Unlock()
+	secretProviders[name] = factory
+}
+
+func NewSecretProvider(name string, cfg *COREConfig) (SecretProvider, error) {
+	secretProvidersMutex.RLock()
+	factory, ok := secretProviders[name]
+	secretProvidersMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown secret provider %q", name)
+	}
+	return factory(cfg)
+}
+
+func secretProviderNames() []string {
+	secretProvidersMutex.RLock()
+	defer secretProvidersMutex.RUnlock()
+	names := make([]string, 0, len(secretProviders))
+	for name := range secretProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseKeyType splits a key_type into where the key is read from, LocalConfig or the name
+// of a secret provider, and what it is, KeyKindPrivateKey, KeyKindMnemonic or KeyKindKeystore.
+// An empty key_type is KeyTypeLocalPrivateKey.
+func ParseKeyType(keyType string) (string, string, error) {
+	if keyType == "" {
+		keyType = KeyTypeLocalPrivateKey
+	}
+	kinds := []string{KeyKindPrivateKey, KeyKindMnemonic, KeyKindKeystore}
+	for _, kind := range kinds {
+		if !strings.HasSuffix(keyType, "_"+kind) {
+			continue
+		}
+		source := strings.TrimSuffix(keyType, "_"+kind)
+		if source == LocalConfig {
+			return source, kind, nil
+		}
+		secretProvidersMutex.RLock()
+		_, ok := secretProviders[source]
+		secretProvidersMutex.RUnlock()
+		if ok {
+			return source, kind, nil
+		}
+	}
+	return "", "", fmt.Errorf("unknown key_type %q, should be <source>_<kind> with source one of %s and kind one of %s",
+		keyType, strings.Join(append([]string{LocalConfig}, secretProviderNames()...), ", "), strings.Join(kinds, ", "))
+}
@@ -12,10 +12,31 @@ import (
 )
 
 func GetSecret(secretName, region string) (string, error) {
+	provider := &AWSSecretProvider{Region: region}
+	return provider.GetSecret(secretName)
+}
+
+// AWSSecretProvider reads secrets from AWS Secrets Manager
+type AWSSecretProvider struct {
+	Region string
+	// Endpoint overrides the Secrets Manager endpoint, e.g. a VPC endpoint or a local stand-in
+	Endpoint string
+}
+
+func newAWSSecretProvider(cfg *COREConfig) (SecretProvider, error) {
+	return &AWSSecretProvider{Region: cfg.AWSRegion, Endpoint: cfg.AWSEndpoint}, nil
+}
+
+func (provider *AWSSecretProvider) GetSecret(secretName string) (string, error) {
+	awsConfig := &aws.Config{
+		Region: aws.String(provider.Region),
+	}
+	if provider.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(provider.Endpoint)
+	}
+
 	//Create a Secrets Manager client
-	sess, err := session.NewSession(&aws.Config{
-		Region: &region,
-	})
+	sess, err := session.NewSession(awsConfig)
 	if err != nil {
 		return "", err
 	}
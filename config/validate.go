@@ -0,0 +1,186 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// FieldError is a single problem found in the config, Field is the json path of the setting
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors collects every problem found in one validation pass
+type ValidationErrors []*FieldError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (errs *ValidationErrors) add(field string, format string, args ...interface{}) {
+	*errs = append(*errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// merge appends the problems reported by a nested Validate
+func (errs *ValidationErrors) merge(err error) {
+	if err == nil {
+		return
+	}
+	if nested, ok := err.(ValidationErrors); ok {
+		*errs = append(*errs, nested...)
+		return
+	}
+	*errs = append(*errs, &FieldError{Message: err.Error()})
+}
+
+func (errs ValidationErrors) errOrNil() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// check the host of a bitcoind rpc endpoint, rpcclient expects host[:port] without scheme
+func checkRpcHost(host string) error {
+	if host == "" {
+		return fmt.Errorf("should not be empty")
+	}
+	if strings.Contains(host, "://") {
+		return fmt.Errorf("%q should be host[:port] without scheme", host)
+	}
+	u, err := url.Parse("http://" + host)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid host: %s", host, err.Error())
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%q has no host name", host)
+	}
+	if port := u.Port(); port != "" {
+		if err := checkPort(port); err != nil {
+			return fmt.Errorf("%q %s", host, err.Error())
+		}
+	}
+	return nil
+}
+
+// check the url of a Core Chain provider
+func checkProviderURL(provider string) error {
+	if provider == "" {
+		return fmt.Errorf("should not be empty")
+	}
+	// ipc endpoint
+	if strings.HasSuffix(provider, ".ipc") && !strings.Contains(provider, "://") {
+		return nil
+	}
+	u, err := url.Parse(provider)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid url: %s", provider, err.Error())
+	}
+	switch u.Scheme {
+	case "http", "https", "ws", "wss":
+	default:
+		return fmt.Errorf("%q should use one of the schemes http, https, ws, wss", provider)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%q has no host name", provider)
+	}
+	if port := u.Port(); port != "" {
+		if err := checkPort(port); err != nil {
+			return fmt.Errorf("%q %s", provider, err.Error())
+		}
+	}
+	return nil
+}
+
+// check the url of an Esplora REST API, e.g. https://blockstream.info/api
+func checkEsploraURL(esplora string) error {
+	if esplora == "" {
+		return fmt.Errorf("should not be empty")
+	}
+	u, err := url.Parse(esplora)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid url: %s", esplora, err.Error())
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q should use one of the schemes http, https", esplora)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%q has no host name", esplora)
+	}
+	if port := u.Port(); port != "" {
+		if err := checkPort(port); err != nil {
+			return fmt.Errorf("%q %s", esplora, err.Error())
+		}
+	}
+	return nil
+}
+
+// check the address of a Bitcoin peer, host:port
+func checkPeerAddr(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("%q should be host:port", addr)
+	}
+	if host == "" {
+		return fmt.Errorf("%q has no host name", addr)
+	}
+	if err := checkPort(port); err != nil {
+		return fmt.Errorf("%q %s", addr, err.Error())
+	}
+	return nil
+}
+
+// check a zmq endpoint of bitcoind, e.g. tcp://127.0.0.1:28332
+func checkZmqAddr(addr string) error {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid url: %s", addr, err.Error())
+	}
+	switch u.Scheme {
+	case "tcp":
+		if u.Hostname() == "" {
+			return fmt.Errorf("%q has no host name", addr)
+		}
+		if err := checkPort(u.Port()); err != nil {
+			return fmt.Errorf("%q %s", addr, err.Error())
+		}
+	case "ipc":
+	default:
+		return fmt.Errorf("%q should use one of the schemes tcp, ipc", addr)
+	}
+	return nil
+}
+
+func checkPort(port string) error {
+	p, err := strconv.Atoi(port)
+	if err != nil || p <= 0 || p > 65535 {
+		return fmt.Errorf("has invalid port %s", port)
+	}
+	return nil
+}
+
+// check a hex encoded secp256k1 private key, 0x prefix is optional
+func checkPrivateKey(key string) error {
+	key = strings.TrimPrefix(key, "0x")
+	if len(key) != 64 {
+		return fmt.Errorf("should be 32 bytes hex encoded, got %d characters", len(key))
+	}
+	for _, c := range key {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return fmt.Errorf("should be hex encoded")
+		}
+	}
+	return nil
+}
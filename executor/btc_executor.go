@@ -1,22 +1,25 @@
 package executor
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"sync"
 	"time"
 
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 
-	"github.com/btcsuite/btcd/rpcclient"
 	"github.com/coredao-org/btc-relayer/common"
 	config "github.com/coredao-org/btc-relayer/config"
 )
 
 type BTCClient struct {
-	BTCClient     *rpcclient.Client
+	BTCClient     BTCSource
 	Provider      string
+	ZmqAddr       string
 	CurrentHeight int64
 	UpdatedAt     time.Time
 }
@@ -27,20 +30,17 @@ type BTCExecutor struct {
 	HighestHeight int64
 	BTCClients    []*BTCClient
 	Config        *config.Config
+	// newTip is signalled when HighestHeight rises
+	newTip    chan struct{}
+	validator *HeaderValidator
 }
 
-func initBTCClients(providers []config.BTCRpcAddrs) []*BTCClient {
+func initBTCClients(btcConfig config.BTCConfig) []*BTCClient {
 	btcClients := make([]*BTCClient, 0)
-	for _, provider := range providers {
+	for _, provider := range btcConfig.RpcAddrs {
 
 		// create new client instance
-		btcClient, err := rpcclient.New(&rpcclient.ConnConfig{
-			HTTPPostMode: true,
-			DisableTLS:   true,
-			Host:         provider.Host,
-			User:         provider.User,
-			Pass:         provider.Pass,
-		}, nil)
+		btcClient, err := newBTCSource(provider, btcConfig.Network)
 		if err != nil {
 			log.Fatalf("error creating new btc client: %v", err)
 		}
@@ -48,6 +48,7 @@ func initBTCClients(providers []config.BTCRpcAddrs) []*BTCClient {
 		btcClients = append(btcClients, &BTCClient{
 			BTCClient: btcClient,
 			Provider:  provider.Host,
+			ZmqAddr:   provider.ZmqAddr,
 			UpdatedAt: time.Now(),
 		})
 	}
@@ -55,14 +56,20 @@ func initBTCClients(providers []config.BTCRpcAddrs) []*BTCClient {
 }
 
 func NewBTCExecutor(cfg *config.Config) (*BTCExecutor, error) {
+	params, err := netParams(cfg.BTCConfig.Network)
+	if err != nil {
+		return nil, err
+	}
 	return &BTCExecutor{
 		clientIdx:  0,
-		BTCClients: initBTCClients(cfg.BTCConfig.RpcAddrs),
+		BTCClients: initBTCClients(cfg.BTCConfig),
 		Config:     cfg,
+		newTip:     make(chan struct{}, 1),
+		validator:  NewHeaderValidator(params),
 	}, nil
 }
 
-func (executor *BTCExecutor) GetClient() *rpcclient.Client {
+func (executor *BTCExecutor) GetClient() BTCSource {
 	executor.mutex.RLock()
 	defer executor.mutex.RUnlock()
 	return executor.BTCClients[executor.clientIdx].BTCClient
@@ -75,10 +82,22 @@ func (executor *BTCExecutor) SwitchBTClient() {
 	if executor.clientIdx >= len(executor.BTCClients) {
 		executor.clientIdx = 0
 	}
-	common.Logger.Infof("Switch to RPC endpoint: %s", executor.Config.BTCConfig.RpcAddrs[executor.clientIdx])
+	addr := executor.Config.BTCConfig.RpcAddrs[executor.clientIdx]
+	common.Logger.Infof("Switch to RPC endpoint: %s %s", addr.GetSource(), addr.Host)
 }
 
-func (executor *BTCExecutor) GetLatestBlockHeight(client *rpcclient.Client) (int64, error) {
+/**
+stop the btc sources keeping a connection, the p2p peers
+*/
+func (executor *BTCExecutor) Close() {
+	for _, btcClient := range executor.BTCClients {
+		if source, ok := btcClient.BTCClient.(*p2pSource); ok {
+			source.stop()
+		}
+	}
+}
+
+func (executor *BTCExecutor) GetLatestBlockHeight(client BTCSource) (int64, error) {
 	height, err := client.GetBlockCount()
 	if err != nil {
 		return 0, err
@@ -86,7 +105,7 @@ func (executor *BTCExecutor) GetLatestBlockHeight(client *rpcclient.Client) (int
 	return height, nil
 }
 
-func (executor *BTCExecutor) GetBlockHash(client *rpcclient.Client, height int64) (*chainhash.Hash, error) {
+func (executor *BTCExecutor) GetBlockHash(client BTCSource, height int64) (*chainhash.Hash, error) {
 	hash, err := client.GetBlockHash(height)
 	if err != nil {
 		return nil, err
@@ -94,7 +113,7 @@ func (executor *BTCExecutor) GetBlockHash(client *rpcclient.Client, height int64
 	return hash, nil
 }
 
-func (executor *BTCExecutor) GetBlock(client *rpcclient.Client, hash *chainhash.Hash) (*wire.MsgBlock, error) {
+func (executor *BTCExecutor) GetBlock(client BTCSource, hash *chainhash.Hash) (*wire.MsgBlock, error) {
 	block, err := client.GetBlock(hash)
 	if err != nil {
 		return nil, err
@@ -102,7 +121,115 @@ func (executor *BTCExecutor) GetBlock(client *rpcclient.Client, hash *chainhash.
 	return block, nil
 }
 
-func (executor *BTCExecutor) UpdateClients() {
+/**
+get the task of a block to relay, fetching only the parts needed for relaying.
+Falls back to the full block if the node can not serve the parts
+*/
+func (executor *BTCExecutor) GetRelayTask(client BTCSource, height int64, hash *chainhash.Hash) (*common.Task, error) {
+	parts, err := executor.GetBlockParts(client, hash)
+	if err == nil {
+		return &common.Task{Height: height, BlockHash: hash, PARTS: parts}, nil
+	}
+	common.Logger.Warningf("get parts of block %s error, get the full block, err=%s", hash.String(), err.Error())
+
+	block, err := executor.GetBlock(client, hash)
+	if err != nil {
+		return nil, err
+	}
+	merkles := blockchain.BuildMerkleTreeStore(btcutil.NewBlock(block).Transactions(), false)
+	if len(merkles) == 0 || *merkles[len(merkles)-1] != block.Header.MerkleRoot {
+		return nil, fmt.Errorf("block %s does not match its merkle root", hash.String())
+	}
+	return &common.Task{Height: height, BlockHash: hash, BLOCK: block}, nil
+}
+
+/**
+the merkle root of the txids of a block, in block order
+*/
+func merkleRootOf(txHashes []chainhash.Hash) chainhash.Hash {
+	level := make([]chainhash.Hash, len(txHashes))
+	copy(level, txHashes)
+	for len(level) > 1 {
+		//an odd level pairs its last hash with itself
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([]chainhash.Hash, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			next = append(next, *blockchain.HashMerkleBranches(&level[i], &level[i+1]))
+		}
+		level = next
+	}
+	return level[0]
+}
+
+/**
+get the parts of a block needed for relaying: the header, the txids and the raw coinbase, instead of the full block
+*/
+func (executor *BTCExecutor) GetBlockParts(client BTCSource, hash *chainhash.Hash) (*common.BlockParts, error) {
+	header, err := client.GetBlockHeader(hash)
+	if err != nil {
+		return nil, err
+	}
+	if header.BlockHash() != *hash {
+		return nil, fmt.Errorf("header of block %s has hash %s", hash.String(), header.BlockHash().String())
+	}
+
+	txHashes, err := client.GetBlockTxHashes(hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(txHashes) == 0 {
+		return nil, fmt.Errorf("block %s has no txs", hash.String())
+	}
+	//a wrong txid list makes a merkle proof the light client rejects with ERR_MERKLE
+	if root := merkleRootOf(txHashes); root != header.MerkleRoot {
+		return nil, fmt.Errorf("txids of block %s have merkle root %s, expected %s", hash.String(), root.String(), header.MerkleRoot.String())
+	}
+
+	coinbase, err := client.GetRawTransaction(&txHashes[0], hash)
+	if err != nil {
+		return nil, err
+	}
+	if coinbase.TxHash() != txHashes[0] {
+		return nil, fmt.Errorf("coinbase of block %s has txid %s, expected %s", hash.String(), coinbase.TxHash().String(), txHashes[0].String())
+	}
+
+	return &common.BlockParts{
+		Header:   *header,
+		Coinbase: coinbase,
+		TxHashes: txHashes,
+	}, nil
+}
+
+/**
+validate the header of a task locally before relaying, with the ancestors from the client the task was fetched from
+*/
+func (executor *BTCExecutor) ValidateHeader(client BTCSource, task *common.Task) error {
+	header := task.Header()
+	err := fmt.Errorf("header has hash %s", header.BlockHash().String())
+	if header.BlockHash() == *task.BlockHash {
+		err = executor.validator.Validate(client, task.Height, header)
+	}
+	if err != nil {
+		return &HeaderError{Height: task.Height, BlockHash: task.BlockHash.String(), Provider: executor.providerOf(client), Err: err}
+	}
+	return nil
+}
+
+func (executor *BTCExecutor) providerOf(client BTCSource) string {
+	for _, btcClient := range executor.BTCClients {
+		if btcClient.BTCClient == client {
+			return btcClient.Provider
+		}
+	}
+	return ""
+}
+
+/**
+refresh the height of every btc endpoint every sleep_second and switch to the highest one, until ctx is done
+*/
+func (executor *BTCExecutor) UpdateClients(ctx context.Context) {
 	for {
 		for _, btcClient := range executor.BTCClients {
 			if time.Since(btcClient.UpdatedAt).Seconds() > executor.Config.BTCConfig.DataSeedDenyServiceThreshold {
@@ -115,27 +242,64 @@ func (executor *BTCExecutor) UpdateClients() {
 				common.Logger.Errorf("get latest block height error, err=%s", err.Error())
 				continue
 			}
-			btcClient.CurrentHeight = height
-			btcClient.UpdatedAt = time.Now()
+			executor.setClientHeight(btcClient, height)
 		}
-		highestHeight := int64(0)
-		highestIdx := 0
-		for idx := 0; idx < len(executor.BTCClients); idx++ {
-			if executor.BTCClients[idx].CurrentHeight > highestHeight {
-				highestHeight = executor.BTCClients[idx].CurrentHeight
-				highestIdx = idx
-			}
+		executor.updateHighestHeight()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(executor.Config.BTCConfig.SleepSecond) * time.Second):
+		}
+	}
+}
+
+func (executor *BTCExecutor) setClientHeight(btcClient *BTCClient, height int64) {
+	executor.mutex.Lock()
+	defer executor.mutex.Unlock()
+	btcClient.CurrentHeight = height
+	btcClient.UpdatedAt = time.Now()
+}
+
+/**
+switch to the client with the highest height, and signal the new tip if it rose
+*/
+func (executor *BTCExecutor) updateHighestHeight() {
+	executor.mutex.Lock()
+	defer executor.mutex.Unlock()
+
+	highestHeight := int64(0)
+	highestIdx := 0
+	for idx := 0; idx < len(executor.BTCClients); idx++ {
+		if executor.BTCClients[idx].CurrentHeight > highestHeight {
+			highestHeight = executor.BTCClients[idx].CurrentHeight
+			highestIdx = idx
 		}
+	}
 
-		//if executor.BTCClients[executor.clientIdx].CurrentHeight+FallBehindThreshold < highestHeight {
-		if highestHeight > executor.HighestHeight {
-			common.Logger.Infof("new height:" + Int64ToString(highestHeight))
+	//if executor.BTCClients[executor.clientIdx].CurrentHeight+FallBehindThreshold < highestHeight {
+	if highestHeight > executor.HighestHeight {
+		common.Logger.Infof("new height:" + Int64ToString(highestHeight))
+		executor.clientIdx = highestIdx
+		executor.HighestHeight = highestHeight
 
-			executor.mutex.Lock()
-			executor.clientIdx = highestIdx
-			executor.HighestHeight = highestHeight
-			executor.mutex.Unlock()
+		select {
+		case executor.newTip <- struct{}{}:
+		default:
 		}
-		time.Sleep(time.Duration(executor.Config.BTCConfig.SleepSecond) * time.Second)
+	}
+}
+
+/**
+wait until HighestHeight rises or the timeout passes, return whether it rose
+*/
+func (executor *BTCExecutor) WaitNewTip(ctx context.Context, timeout time.Duration) bool {
+	select {
+	case <-executor.newTip:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-time.After(timeout):
+		return false
 	}
 }
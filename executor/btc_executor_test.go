@@ -3,6 +3,10 @@ package executor
 import (
 	"testing"
 
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/stretchr/testify/require"
 )
 
@@ -37,3 +41,105 @@ func TestGetBlock(t *testing.T) {
 	block, err := BTCExecutor.GetBlock(BTCExecutor.GetClient(), hash)
 	require.NotNilf(t, block, "error")
 }
+
+// newTestBlock is a block with a segwit coinbase and txs more than a merkle level deep
+func newTestBlock(t *testing.T) *wire.MsgBlock {
+	coinbase := wire.NewMsgTx(2)
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, 0xffffffff), []byte{0x03, 0x40, 0x0d, 0x03}, [][]byte{make([]byte, 32)}))
+	coinbase.AddTxOut(wire.NewTxOut(625000000, []byte{0x00, 0x14, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13, 0x14}))
+	coinbase.AddTxOut(wire.NewTxOut(0, []byte{0x6a, 0x24, 0xaa, 0x21, 0xa9, 0xed}))
+
+	block := wire.NewMsgBlock(wire.NewBlockHeader(0x20000000, &chainhash.Hash{0x01}, &chainhash.Hash{}, 0x17053894, 42))
+	require.NoError(t, block.AddTransaction(coinbase))
+	for i := 0; i < 6; i++ {
+		tx := wire.NewMsgTx(2)
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{byte(i)}, uint32(i)), nil, [][]byte{{byte(i)}, make([]byte, 33)}))
+		tx.AddTxOut(wire.NewTxOut(int64(1000*(i+1)), []byte{0x51}))
+		require.NoError(t, block.AddTransaction(tx))
+	}
+	merkles := blockchain.BuildMerkleTreeStore(btcutil.NewBlock(block).Transactions(), false)
+	block.Header.MerkleRoot = *merkles[len(merkles)-1]
+	return block
+}
+
+func TestGetBlockParts_SameMirror(t *testing.T) {
+	bitcoind, server := newFakeBitcoind(t)
+	defer server.Close()
+	block := newTestBlock(t)
+	bitcoind.addBlock(block, 42)
+	executor := newTestBTCExecutor(t, server.URL)
+	blockHash := block.BlockHash()
+
+	task, err := executor.GetRelayTask(executor.GetClient(), 42, &blockHash)
+	require.NoError(t, err)
+	require.NotNil(t, task.PARTS)
+	require.Nil(t, task.BLOCK)
+	// verbosity 1 only, no full block
+	require.Equal(t, 1, bitcoind.calls("getblock"))
+
+	fromParts, err := serializeBtcLightMirror(NewBtcLightMirrorFromParts(task.PARTS))
+	require.NoError(t, err)
+
+	fullBlock, err := executor.GetBlock(executor.GetClient(), &blockHash)
+	require.NoError(t, err)
+	fromBlock, err := serializeBtcLightMirror(NewBtcLightMirror(fullBlock))
+	require.NoError(t, err)
+	require.Equal(t, fromBlock, fromParts)
+}
+
+func TestGetRelayTask_FallbackToBlock(t *testing.T) {
+	bitcoind, server := newFakeBitcoind(t)
+	defer server.Close()
+	block := newTestBlock(t)
+	bitcoind.addBlock(block, 42)
+	executor := newTestBTCExecutor(t, server.URL)
+
+	// a node that does not serve the txs of a block
+	bitcoind.unsupported["getrawtransaction"] = true
+	blockHash := block.BlockHash()
+
+	_, err := executor.GetBlockParts(executor.GetClient(), &blockHash)
+	require.Error(t, err)
+	task, err := executor.GetRelayTask(executor.GetClient(), 42, &blockHash)
+	require.NoError(t, err)
+	require.Nil(t, task.PARTS)
+	require.Equal(t, block.BlockHash(), task.BLOCK.BlockHash())
+	require.Equal(t, fillTxHashes(block.Transactions), fillTxHashes(task.BLOCK.Transactions))
+}
+
+func TestMerkleRootOf(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 7} {
+		block := newTestBlock(t)
+		block.Transactions = block.Transactions[:n]
+		merkles := blockchain.BuildMerkleTreeStore(btcutil.NewBlock(block).Transactions(), false)
+		require.Equal(t, *merkles[len(merkles)-1], merkleRootOf(fillTxHashes(block.Transactions)))
+	}
+}
+
+func TestGetRelayTask_TamperedTxids(t *testing.T) {
+	bitcoind, server := newFakeBitcoind(t)
+	defer server.Close()
+	block := newTestBlock(t)
+	bitcoind.addBlock(block, 42)
+	executor := newTestBTCExecutor(t, server.URL)
+	blockHash := block.BlockHash()
+
+	// the provider serves the header with the txs of another block
+	bitcoind.tamper = func(block *wire.MsgBlock) *wire.MsgBlock {
+		tampered := *block
+		tampered.Transactions = append([]*wire.MsgTx{}, block.Transactions...)
+		tampered.Transactions[0], tampered.Transactions[1] = block.Transactions[1], block.Transactions[0]
+		return &tampered
+	}
+	_, err := executor.GetBlockParts(executor.GetClient(), &blockHash)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "merkle root")
+	// the full block from the same provider is rejected too
+	_, err = executor.GetRelayTask(executor.GetClient(), 42, &blockHash)
+	require.Error(t, err)
+
+	bitcoind.tamper = nil
+	task, err := executor.GetRelayTask(executor.GetClient(), 42, &blockHash)
+	require.NoError(t, err)
+	require.NotNil(t, task.PARTS)
+}
@@ -0,0 +1,130 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/btcsuite/btcd/wire"
+
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+// BTCSource is where the BTC chain data is fetched from, a bitcoind node, an Esplora REST API or a Bitcoin peer
+type BTCSource interface {
+	// GetBlockCount is the height of the tip
+	GetBlockCount() (int64, error)
+	GetBlockHash(height int64) (*chainhash.Hash, error)
+	GetBlockHeader(hash *chainhash.Hash) (*wire.BlockHeader, error)
+	GetBlockHeight(hash *chainhash.Hash) (int64, error)
+	// GetBlockTxHashes are the txids of the block in order, the coinbase first
+	GetBlockTxHashes(hash *chainhash.Hash) ([]chainhash.Hash, error)
+	// GetRawTransaction gets a tx of the block, without needing a tx index
+	GetRawTransaction(txHash *chainhash.Hash, blockHash *chainhash.Hash) (*wire.MsgTx, error)
+	GetBlock(hash *chainhash.Hash) (*wire.MsgBlock, error)
+}
+
+func newBTCSource(provider config.BTCRpcAddrs, network string) (BTCSource, error) {
+	switch provider.GetSource() {
+	case config.BTCSourceBitcoind:
+		return newBitcoindSource(provider)
+	case config.BTCSourceEsplora:
+		return newEsploraSource(provider.Host), nil
+	case config.BTCSourceP2P:
+		params, err := netParams(network)
+		if err != nil {
+			return nil, err
+		}
+		return newP2PSource(provider.Host, params), nil
+	}
+	return nil, fmt.Errorf("unknown btc source %q", provider.Source)
+}
+
+// bitcoindSource is the json-rpc of bitcoind in HTTP POST mode
+type bitcoindSource struct {
+	*rpcclient.Client
+}
+
+func newBitcoindSource(provider config.BTCRpcAddrs) (*bitcoindSource, error) {
+	client, err := rpcclient.New(&rpcclient.ConnConfig{
+		HTTPPostMode: true,
+		DisableTLS:   true,
+		Host:         provider.Host,
+		User:         provider.User,
+		Pass:         provider.Pass,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &bitcoindSource{Client: client}, nil
+}
+
+func (source *bitcoindSource) GetBlockHeight(hash *chainhash.Hash) (int64, error) {
+	header, err := source.GetBlockHeaderVerbose(hash)
+	if err != nil {
+		return 0, err
+	}
+	return int64(header.Height), nil
+}
+
+/**
+getblock with verbosity 1
+*/
+func (source *bitcoindSource) GetBlockTxHashes(hash *chainhash.Hash) ([]chainhash.Hash, error) {
+	verbose, err := source.GetBlockVerbose(hash)
+	if err != nil {
+		return nil, err
+	}
+	return parseTxHashes(verbose.Tx)
+}
+
+/**
+getrawtransaction with the block hash, which works without txindex
+*/
+func (source *bitcoindSource) GetRawTransaction(txHash *chainhash.Hash, blockHash *chainhash.Hash) (*wire.MsgTx, error) {
+	params := make([]json.RawMessage, 0, 3)
+	for _, param := range []interface{}{txHash.String(), false, blockHash.String()} {
+		bz, err := json.Marshal(param)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, bz)
+	}
+	res, err := source.RawRequest("getrawtransaction", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var txHex string
+	if err := json.Unmarshal(res, &txHex); err != nil {
+		return nil, err
+	}
+	return deserializeTx(txHex)
+}
+
+func parseTxHashes(txids []string) ([]chainhash.Hash, error) {
+	txHashes := make([]chainhash.Hash, len(txids))
+	for i, txid := range txids {
+		txHash, err := chainhash.NewHashFromStr(txid)
+		if err != nil {
+			return nil, err
+		}
+		txHashes[i] = *txHash
+	}
+	return txHashes, nil
+}
+
+func deserializeTx(txHex string) (*wire.MsgTx, error) {
+	bz, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, err
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	if err := tx.Deserialize(bytes.NewReader(bz)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
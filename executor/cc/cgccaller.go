@@ -14,7 +14,9 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
 )
 
@@ -60,13 +62,18 @@ func bindCGCCaller(address common.Address, caller bind.ContractCaller, transacto
 relayed btc block
 */
 func (_CGC *CGCCaller) SyncBtcHeader(opts *bind.TransactOpts, lightClient []byte) (common.Hash, error) {
-	out, err := _CGC.contract.Transact(opts, "storeBlockHeader", lightClient)
+	out, err := _CGC.StoreBlockHeader(opts, lightClient)
 	if err != nil {
 		return common.Hash{}, err
 	}
 	return out.Hash(), err
 }
 
+// StoreBlockHeader is a paid mutator transaction binding the contract method storeBlockHeader.
+func (_CGC *CGCCaller) StoreBlockHeader(opts *bind.TransactOpts, lightClient []byte) (*types.Transaction, error) {
+	return _CGC.contract.Transact(opts, "storeBlockHeader", lightClient)
+}
+
 /**
 check whether the block is relayed
 */
@@ -99,6 +106,36 @@ func (_CGC *CGCCaller) GetChainTip(opts *bind.CallOpts) (*chainhash.Hash, error)
 	return &chainTip, err
 }
 
+/**
+query the previous block hash of a relayed block
+*/
+func (_CGC *CGCCaller) GetPrevHash(opts *bind.CallOpts, appHash *chainhash.Hash) (*chainhash.Hash, error) {
+	retval := make([]interface{}, 0, 1)
+	err := _CGC.contract.Call(opts, &retval, "getPrevHash", [32]byte(*appHash))
+	if err != nil {
+		return nil, err
+	}
+
+	bts := retval[0].([32]byte)
+	prevHash := chainhash.Hash{}
+	err = prevHash.SetBytes(bts[:])
+
+	return &prevHash, err
+}
+
+/**
+query the btc height of a relayed block
+*/
+func (_CGC *CGCCaller) GetHeight(opts *bind.CallOpts, appHash *chainhash.Hash) (uint32, error) {
+	retval := make([]interface{}, 0, 1)
+	err := _CGC.contract.Call(opts, &retval, "getHeight", [32]byte(*appHash))
+	if err != nil {
+		return 0, err
+	}
+
+	return retval[0].(uint32), nil
+}
+
 /**
 query who submitted the block
 */
@@ -112,3 +149,64 @@ func (_CGC *CGCCaller) QuerySubmitters(opts *bind.CallOpts, appHash *chainhash.H
 	ret0 := fmt.Sprint(retval[0])
 	return ret0, nil
 }
+
+/**
+pack the call data of storeBlockHeader
+*/
+func PackSyncBtcHeader(lightClient []byte) ([]byte, error) {
+	parsed, err := abi.JSON(strings.NewReader(CGCABI))
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Pack("storeBlockHeader", lightClient)
+}
+
+/**
+unpack the return code of storeBlockHeader from the output of eth_call
+*/
+func UnpackStoreBlockHeader(output []byte) (uint32, error) {
+	parsed, err := abi.JSON(strings.NewReader(CGCABI))
+	if err != nil {
+		return 0, err
+	}
+	ret, err := parsed.Unpack("storeBlockHeader", output)
+	if err != nil {
+		return 0, err
+	}
+	return ret[0].(uint32), nil
+}
+
+/**
+query an int256 constant of the light client, e.g. ERR_NO_PREV_BLOCK
+*/
+func (_CGC *CGCCaller) GetInt256Constant(opts *bind.CallOpts, name string) (*big.Int, error) {
+	retval := make([]interface{}, 0, 1)
+	err := _CGC.contract.Call(opts, &retval, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return retval[0].(*big.Int), nil
+}
+
+// StoreHeader is the StoreHeader(blockHash, returnCode) event of storeBlockHeader
+type StoreHeader struct {
+	BlockHash  common.Hash
+	ReturnCode *big.Int
+}
+
+// StoreHeaderEventID is the topic of the StoreHeader event
+var StoreHeaderEventID = crypto.Keccak256Hash([]byte("StoreHeader(bytes32,int256)"))
+
+/**
+parse a StoreHeader event, both fields are indexed
+*/
+func ParseStoreHeader(log types.Log) (*StoreHeader, bool) {
+	if len(log.Topics) != 3 || log.Topics[0] != StoreHeaderEventID {
+		return nil, false
+	}
+	return &StoreHeader{
+		BlockHash:  log.Topics[1],
+		ReturnCode: math.S256(log.Topics[2].Big()),
+	}, true
+}
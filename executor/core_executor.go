@@ -3,7 +3,6 @@ package executor
 import (
 	"bytes"
 	"context"
-	"crypto/ecdsa"
 	"fmt"
 	"log"
 	"math/big"
@@ -21,7 +20,6 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 
 	"github.com/jinzhu/gorm"
@@ -29,6 +27,7 @@ import (
 	relayercommon "github.com/coredao-org/btc-relayer/common"
 	config "github.com/coredao-org/btc-relayer/config"
 	"github.com/coredao-org/btc-relayer/executor/relayerhub"
+	"github.com/coredao-org/btc-relayer/model"
 )
 
 type COREClient struct {
@@ -44,21 +43,13 @@ type COREExecutor struct {
 	btcExecutor *BTCExecutor
 	clientIdx   int
 	coreClients []*COREClient
-	privateKey  *ecdsa.PrivateKey
+	signer      Signer
 	txSender    common.Address
+	nonces      *NonceManager
 	cfg         *config.Config
-}
-
-func getPrivateKey(cfg *config.COREConfig) (*ecdsa.PrivateKey, error) {
-	var privateKey string
 
-	privateKey = cfg.PrivateKey
-
-	privKey, err := crypto.HexToECDSA(privateKey)
-	if err != nil {
-		return nil, err
-	}
-	return privKey, nil
+	returnCodesOnce sync.Once
+	returnCodes     map[int64]error
 }
 
 func initClients(providers []string) []*COREClient {
@@ -79,27 +70,30 @@ func initClients(providers []string) []*COREClient {
 	return clients
 }
 
-func NewCOREExecutor(cfg *config.Config) (*COREExecutor, error) {
-	privKey, err := getPrivateKey(&cfg.COREConfig)
+/**
+create the Core executor, db is the relay state store, relay attempts are not recorded if it is nil
+*/
+func NewCOREExecutor(db *gorm.DB, cfg *config.Config) (*COREExecutor, error) {
+	signer, err := NewSigner(&cfg.COREConfig)
 	if err != nil {
 		return nil, err
 	}
-	publicKey := privKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("get public key error")
-	}
-	txSender := crypto.PubkeyToAddress(*publicKeyECDSA)
 
-	return &COREExecutor{
-		db:          nil,
+	executor := &COREExecutor{
+		db:          db,
 		btcExecutor: nil,
 		clientIdx:   0,
 		coreClients: initClients(cfg.COREConfig.Providers),
-		privateKey:  privKey,
-		txSender:    txSender,
+		signer:      signer,
+		txSender:    signer.Address(),
 		cfg:         cfg,
-	}, nil
+	}
+	executor.nonces = newNonceManager(executor.pendingNonce)
+	return executor, nil
+}
+
+func (executor *COREExecutor) pendingNonce(ctx context.Context) (uint64, error) {
+	return executor.GetClient().PendingNonceAt(ctx, executor.txSender)
 }
 
 func (executor *COREExecutor) GetClient() *ethclient.Client {
@@ -129,7 +123,10 @@ func (executor *COREExecutor) GetLatestBlockHeight(client *ethclient.Client) (in
 	return block.Number().Int64(), nil
 }
 
-func (executor *COREExecutor) UpdateClients() {
+/**
+refresh the height of every provider every sleep_second and switch to the highest one if the current one falls behind, until ctx is done
+*/
+func (executor *COREExecutor) UpdateClients(ctx context.Context) {
 	for {
 		for _, client := range executor.coreClients {
 			if time.Since(client.UpdatedAt).Seconds() > executor.cfg.COREConfig.DataSeedDenyServiceThreshold {
@@ -161,40 +158,88 @@ func (executor *COREExecutor) UpdateClients() {
 			executor.clientIdx = highestIdx
 			executor.mutex.Unlock()
 		}
-		time.Sleep(time.Duration(executor.cfg.COREConfig.SleepSecond) * time.Second)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(executor.cfg.COREConfig.SleepSecond) * time.Second):
+		}
 	}
 }
 
-func (executor *COREExecutor) getTransactor(nonce uint64) (*bind.TransactOpts, error) {
-	chainId, err := executor.GetClient().ChainID(context.Background())
+func (executor *COREExecutor) getTransactor(ctx context.Context, nonce uint64) (*bind.TransactOpts, error) {
+	chainId, err := executor.GetClient().ChainID(ctx)
 	if err != nil {
-		return nil, nil
+		return nil, err
 	}
 
-	txOpts, err := bind.NewKeyedTransactorWithChainID(executor.privateKey, chainId)
+	txOpts := &bind.TransactOpts{
+		From:    executor.txSender,
+		Context: ctx,
+	}
+	txOpts.Signer = func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		if address != executor.txSender {
+			return nil, bind.ErrNotAuthorized
+		}
+		return executor.signer.SignTx(txOpts.Context, tx, chainId)
+	}
 	txOpts.Nonce = big.NewInt(int64(nonce))
 	txOpts.Value = big.NewInt(0)
 	txOpts.GasLimit = executor.cfg.COREConfig.GasLimit
-	if executor.cfg.COREConfig.GasPrice == 0 {
-		txOpts.GasPrice = big.NewInt(DefaultGasPrice)
-	} else {
-		txOpts.GasPrice = big.NewInt(int64(executor.cfg.COREConfig.GasPrice))
+
+	feeDecision, err := executor.decideFee(txOpts.Context)
+	if err != nil {
+		return nil, err
 	}
+	feeDecision.Apply(txOpts)
+	relayercommon.Logger.Infof("fee decision, nonce:%d, %s", nonce, feeDecision.String())
 	return txOpts, nil
 }
 
-func (executor *COREExecutor) GetChainTip() (*chainhash.Hash, error) {
-	callOpts, err := executor.getCallOpts()
+func (executor *COREExecutor) GetChainTip(ctx context.Context) (*chainhash.Hash, error) {
+	callOpts, err := executor.getCallOpts(ctx)
 	instance, err := cgccaller.NewCGCCaller(pcsAddr, executor.GetClient())
 	chainTip, err := instance.GetChainTip(callOpts)
 
 	return chainTip, err
 }
 
-func (executor *COREExecutor) getCallOpts() (*bind.CallOpts, error) {
+/**
+the previous block of a block relayed to the light client, hashes in btc order
+*/
+func (executor *COREExecutor) GetPrevHash(ctx context.Context, blockHash *chainhash.Hash) (*chainhash.Hash, error) {
+	callOpts, _ := executor.getCallOpts(ctx)
+	instance, err := cgccaller.NewCGCCaller(pcsAddr, executor.GetClient())
+	if err != nil {
+		return nil, err
+	}
+	prevHash, err := instance.GetPrevHash(callOpts, RevertHash(blockHash))
+	if err != nil {
+		return nil, err
+	}
+	return RevertHash(prevHash), nil
+}
+
+/**
+the btc height of a block relayed to the light client, as the light client sees it
+*/
+func (executor *COREExecutor) GetHeight(ctx context.Context, blockHash *chainhash.Hash) (int64, error) {
+	callOpts, _ := executor.getCallOpts(ctx)
+	instance, err := cgccaller.NewCGCCaller(pcsAddr, executor.GetClient())
+	if err != nil {
+		return 0, err
+	}
+	height, err := instance.GetHeight(callOpts, RevertHash(blockHash))
+	if err != nil {
+		return 0, err
+	}
+	return int64(height), nil
+}
+
+func (executor *COREExecutor) getCallOpts(ctx context.Context) (*bind.CallOpts, error) {
 	callOpts := &bind.CallOpts{
 		Pending: true,
-		Context: context.Background(),
+		Context: ctx,
 	}
 	return callOpts, nil
 }
@@ -202,92 +247,233 @@ func (executor *COREExecutor) getCallOpts() (*bind.CallOpts, error) {
 /**
 sync BTCLightMirror
 */
-func (executor *COREExecutor) SyncBTCLightMirror(task *relayercommon.Task) (common.Hash, error) {
-	nonce, err := executor.GetClient().PendingNonceAt(context.Background(), executor.txSender)
+func (executor *COREExecutor) SyncBTCLightMirror(ctx context.Context, task *relayercommon.Task) (common.Hash, error) {
+	submission, err := executor.SubmitBTCLightMirror(ctx, task)
 	if err != nil {
 		return common.Hash{}, err
 	}
-	txOpts, err := executor.getTransactor(nonce)
-	if err != nil && txOpts != nil {
-		return common.Hash{}, err
+	return executor.WaitBTCLightMirror(ctx, submission)
+}
+
+// RelaySubmission is a relay tx that is sent and not waited for yet
+type RelaySubmission struct {
+	Task     *relayercommon.Task
+	Tx       *types.Transaction
+	bts      []byte
+	gasLimit uint64
+	// attemptID is the row of the tx in the relay state store, 0 if not recorded
+	attemptID int64
+}
+
+/**
+simulate and send the relay tx of task without waiting for its receipt,
+so several headers can be submitted back-to-back with sequential nonces
+*/
+func (executor *COREExecutor) SubmitBTCLightMirror(ctx context.Context, task *relayercommon.Task) (*RelaySubmission, error) {
+	payload, err := BuildRelayPayload(task)
+	if err != nil {
+		return nil, err
 	}
+	return executor.SubmitRelayPayload(ctx, payload, true)
+}
 
-	mirror := NewBtcLightMirror(task.BLOCK)
+// RelayPayload is the serialized BtcLightMirrorV2 of a task and the storeBlockHeader call data
+type RelayPayload struct {
+	Task *relayercommon.Task
+	bts  []byte
+	data []byte
+}
 
-	for {
-		txHash, err := executor.syncBtcHeader(mirror, task.BlockHash)
+// To is the light client contract the relay tx is sent to
+func (payload *RelayPayload) To() string {
+	return pcsAddr.String()
+}
+
+// Data is the storeBlockHeader call data of the relay tx
+func (payload *RelayPayload) Data() []byte {
+	return payload.data
+}
+
+// MirrorSize is the size of the serialized BtcLightMirrorV2
+func (payload *RelayPayload) MirrorSize() int {
+	return len(payload.bts)
+}
+
+func BuildRelayPayload(task *relayercommon.Task) (*RelayPayload, error) {
+	mirror := newTaskLightMirror(task)
+	bts, err := serializeBtcLightMirror(mirror)
+	if err != nil {
+		return nil, err
+	}
+	data, err := cgccaller.PackSyncBtcHeader(bts)
+	if err != nil {
+		return nil, err
+	}
+	return &RelayPayload{Task: task, bts: bts, data: data}, nil
+}
+
+/**
+send the relay tx of payload. With simulate a header the light client rejects is returned before sending and the gas
+is estimated, without it gas_limit is used, for a header whose previous header is still pending
+*/
+func (executor *COREExecutor) SubmitRelayPayload(ctx context.Context, payload *RelayPayload, simulate bool) (*RelaySubmission, error) {
+	task := payload.Task
+	gasLimit := executor.cfg.COREConfig.GasLimit
+	if simulate {
+		//simulate before sending, a rejected header is not retryable
+		var err error
+		gasLimit, err = executor.simulateRelay(ctx, payload.data, task.BlockHash)
 		if err != nil {
-			return common.Hash{}, err
+			brcommon.Logger.Errorf("simulate relaying failed, blockHash:%s height:%d, err=%s", task.BlockHash.String(), task.Height, err.Error())
+			executor.recordFailedAttempt(task, payload.bts, gasLimit, model.OutcomeSimulationFailed, err)
+			return nil, err
 		}
+	}
+
+	return executor.submitRelay(ctx, task, payload.bts, gasLimit)
+}
 
-		brcommon.Logger.Infof("submit transaction, blockHash:" + task.BlockHash.String() + " height:" + Int64ToString(task.Height) + ",txHash:" + txHash.String() + ", start to check relaying result")
+func (executor *COREExecutor) submitRelay(ctx context.Context, task *relayercommon.Task, bts []byte, gasLimit uint64) (*RelaySubmission, error) {
+	tx, err := executor.syncBtcHeader(ctx, bts, task.BlockHash, gasLimit)
+	if err != nil {
+		executor.recordFailedAttempt(task, bts, gasLimit, model.OutcomeSendFailed, err)
+		return nil, err
+	}
+	brcommon.Logger.Infof("submit transaction, blockHash:" + task.BlockHash.String() + " height:" + Int64ToString(task.Height) + ",txHash:" + tx.Hash().String() + ",nonce:" + strconv.FormatUint(tx.Nonce(), 10))
+	attemptID := executor.recordSentAttempt(task, bts, tx)
+	return &RelaySubmission{Task: task, Tx: tx, bts: bts, gasLimit: gasLimit, attemptID: attemptID}, nil
+}
 
-		relayed, retry, err := executor.CheckSuccessRelayed(task.BlockHash, txHash)
+/**
+wait for the outcome of a submitted relay tx, resending it with more gas if it runs out of gas.
+If ctx is done first the tx is left pending in the relay state store, to be resumed on the next start
+*/
+func (executor *COREExecutor) WaitBTCLightMirror(ctx context.Context, submission *RelaySubmission) (common.Hash, error) {
+	task := submission.Task
+	for {
+		result := executor.waitRelay(ctx, task.BlockHash, submission.attemptID, []*types.Transaction{submission.Tx})
+		if ctx.Err() != nil {
+			return result.TxHash, fmt.Errorf("stop waiting for relay tx %s: %w", result.TxHash.String(), ctx.Err())
+		}
+		executor.settleNonce(submission.Tx.Nonce(), result)
+		executor.settleAttempt(submission.attemptID, submission.Tx, result)
+		txHash := result.TxHash
+		brcommon.Logger.Infof("relay outcome:%s, blockHash:%s, txHash:%s, sent:%v", result.Outcome, task.BlockHash.String(), txHash.String(), result.TxHashes)
+
+		switch result.Outcome {
+		case TxIncluded:
+			return txHash, nil
+		case TxSuperseded:
+			brcommon.Logger.Infof("relayed by:[%s]", result.Submitter)
+			return txHash, nil
+		case TxDropped:
+			return txHash, fmt.Errorf("%w, txHash:%s", ErrTxDropped, txHash.String())
+		case TxTimedOut:
+			return txHash, fmt.Errorf("%w, txHash:%s", ErrReceiptTimeout, txHash.String())
+		}
+		if !result.OutOfGas {
+			return txHash, result.Err
+		}
 
-		if relayed || !retry {
-			return common.Hash{}, err
+		gasLimit, increased := executor.increaseGas(submission.gasLimit)
+		if !increased {
+			return txHash, fmt.Errorf("out of gas with max_gas_limit %d", gasLimit)
 		}
+		brcommon.Logger.Infof("gas not enough, increase gas to:" + strconv.FormatUint(gasLimit, 10))
 
-		if retry {
-			executor.IncreaseGas()
+		resubmission, err := executor.submitRelay(ctx, task, submission.bts, gasLimit)
+		if err != nil {
+			return txHash, err
 		}
+		submission = resubmission
+	}
+}
 
+/**
+update the nonce manager with the outcome of the tx of nonce
+*/
+func (executor *COREExecutor) settleNonce(nonce uint64, result *RelayResult) {
+	switch {
+	case result.Receipt != nil && result.Outcome != TxTimedOut:
+		executor.nonces.Confirm(nonce)
+	case result.Outcome == TxDropped:
+		executor.releaseNonce(nonce)
+	case result.Outcome == TxSuperseded:
+		//a competitor stored the header before our tx was included, the tx holds the nonce if it is still known
+		pending, err := executor.pendingNonce(context.Background())
+		if err == nil && pending > nonce {
+			executor.nonces.Confirm(nonce)
+			return
+		}
+		executor.releaseNonce(nonce)
 	}
+}
 
-	return common.Hash{}, err
+func (executor *COREExecutor) releaseNonce(nonce uint64) {
+	executor.nonces.Release(nonce)
+	if err := executor.nonces.Resync(context.Background()); err != nil {
+		brcommon.Logger.Warningf("resync nonce error, err=%s", err.Error())
+	}
 }
 
 /**
-increase gas
+wait for the outcome of the relay tx and its replacements, bounded by confirm_timeout_second
 */
-func (executor *COREExecutor) IncreaseGas() {
-	executor.cfg.COREConfig.GasLimit += executor.cfg.COREConfig.GasIncrease
-	brcommon.Logger.Infof("gas not enough, increase gas to:" + strconv.FormatUint(executor.cfg.COREConfig.GasLimit, 10))
+func (executor *COREExecutor) CheckSuccessRelayed(ctx context.Context, btcBlockHash *chainhash.Hash, coreTx *types.Transaction) *RelayResult {
+	return executor.newReceiptTracker().Wait(ctx, btcBlockHash, coreTx)
 }
 
 /**
-check if btc block is successfully relayed
-return bool:relayed success bool:retry
+wait for the outcome of the relay txs of an attempt like CheckSuccessRelayed, recording every replacement sent
+against the attempt so a restart resumes on it
 */
-func (executor *COREExecutor) CheckSuccessRelayed(btcBlockHash *chainhash.Hash, coreTxHash common.Hash) (bool, bool, error) {
+func (executor *COREExecutor) waitRelay(ctx context.Context, btcBlockHash *chainhash.Hash, attemptID int64, txs []*types.Transaction) *RelayResult {
+	tracker := executor.newReceiptTracker()
+	tracker.onReplace = func(replacement *types.Transaction) {
+		executor.recordReplacement(attemptID, replacement)
+	}
+	return tracker.waitChain(ctx, btcBlockHash, txs)
+}
 
-	for {
-		//CheckBlockRelayed
-		relayed, err := executor.CheckBlockRelayed(btcBlockHash)
-		if err == nil && relayed {
-			submitter, err := executor.QuerySubmitters(btcBlockHash)
-			if err == nil && submitter != "" {
-				brcommon.Logger.Infof("successful, relayed by:[" + submitter + "]")
-			} else {
-				brcommon.Logger.Infof("successful")
-			}
-			return true, false, nil
+/**
+find the StoreHeader event of btcBlockHash in the receipt, a nonzero return code is an error
+*/
+func (executor *COREExecutor) relayErrorOfReceipt(btcBlockHash *chainhash.Hash, receipt *types.Receipt) *RelayError {
+	bHash := common.Hash(*RevertHash(btcBlockHash))
+	for _, log := range receipt.Logs {
+		event, ok := cgccaller.ParseStoreHeader(*log)
+		if !ok || event.BlockHash != bHash || event.ReturnCode.Sign() == 0 {
+			continue
 		}
-
-		//Check TX
-		txRecipient, err := executor.GetTxRecipient(coreTxHash)
-
-		//failed, get revert reason
-		if err == nil {
-			if txRecipient.Status == 0 {
-				tx, _, err := executor.TransactionByHash(coreTxHash)
-				if err == nil {
-
-					//out of gas
-					if tx.Gas() == txRecipient.GasUsed {
-						brcommon.Logger.Infof("out of gas, retry")
-						return false, true, nil
-					}
-					brcommon.Logger.Infof("failed")
-					return false, false, fmt.Errorf("tx failed")
-				}
-			}
+		return &RelayError{
+			BlockHash:  btcBlockHash.String(),
+			TxHash:     receipt.TxHash,
+			ReturnCode: event.ReturnCode.Int64(),
+			Err:        executor.errorOfReturnCode(event.ReturnCode.Int64()),
 		}
-
-		relayercommon.Logger.Infof("relaying, continue to check")
-		time.Sleep(time.Duration(500) * time.Millisecond)
 	}
+	return nil
+}
 
+/**
+replay a reverted tx on the parent block to get the revert reason
+*/
+func (executor *COREExecutor) relayErrorOfRevert(btcBlockHash *chainhash.Hash, tx *types.Transaction, receipt *types.Receipt) *RelayError {
+	relayErr := &RelayError{
+		BlockHash: btcBlockHash.String(),
+		TxHash:    tx.Hash(),
+		Err:       ErrRelayFailed,
+	}
+	var blockNumber *big.Int
+	if receipt.BlockNumber != nil && receipt.BlockNumber.Sign() > 0 {
+		blockNumber = new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+	}
+	_, err := executor.EthCall(tx, blockNumber)
+	if reason, reverted := revertReason(err); reverted {
+		relayErr.Reason = reason
+		relayErr.Err = errorOfRevertReason(reason)
+	}
+	return relayErr
 }
 
 func serializeBtcLightMirror(mirror *lightmirror.BtcLightMirrorV2) ([]byte, error) {
@@ -304,7 +490,7 @@ func (executor *COREExecutor) IsRelayer() (bool, error) {
 		return false, err
 	}
 
-	callOpts, err := executor.getCallOpts()
+	callOpts, err := executor.getCallOpts(context.Background())
 	if err != nil {
 		return false, err
 	}
@@ -317,39 +503,66 @@ func (executor *COREExecutor) IsRelayer() (bool, error) {
 }
 
 func (executor *COREExecutor) RegisterRelayer() (common.Hash, error) {
-	nonce, err := executor.GetClient().PendingNonceAt(context.Background(), executor.txSender)
+	instance, err := relayerhub.NewRelayerhub(relayerHubContractAddr, executor.GetClient())
 	if err != nil {
 		return common.Hash{}, err
 	}
-	txOpts, err := executor.getTransactor(nonce)
+
+	var tx *types.Transaction
+	_, err = executor.nonces.Send(context.Background(), func(nonce uint64) error {
+		txOpts, err := executor.getTransactor(context.Background(), nonce)
+		if err != nil {
+			return err
+		}
+		txOpts.Value = big.NewInt(1).Mul(big.NewInt(100), big.NewInt(1e18)) //100 Core
+		tx, err = instance.Register(txOpts)
+		return err
+	})
 	if err != nil {
 		return common.Hash{}, err
 	}
+	return tx.Hash(), nil
+}
 
+func (executor *COREExecutor) UnregisterRelayer() (common.Hash, error) {
 	instance, err := relayerhub.NewRelayerhub(relayerHubContractAddr, executor.GetClient())
 	if err != nil {
 		return common.Hash{}, err
 	}
 
-	txOpts.Value = big.NewInt(1).Mul(big.NewInt(100), big.NewInt(1e18)) //100 Core
-	tx, err := instance.Register(txOpts)
+	var tx *types.Transaction
+	_, err = executor.nonces.Send(context.Background(), func(nonce uint64) error {
+		txOpts, err := executor.getTransactor(context.Background(), nonce)
+		if err != nil {
+			return err
+		}
+		tx, err = instance.Unregister(txOpts)
+		return err
+	})
 	if err != nil {
 		return common.Hash{}, err
 	}
 	return tx.Hash(), nil
 }
 
+func (executor *COREExecutor) GetRelayerAddress() common.Address {
+	return executor.txSender
+}
+
 func (executor *COREExecutor) EthCall(tx *types.Transaction, blockNumber *big.Int) ([]byte, error) {
 	msg := ethereum.CallMsg{
-		From:     executor.txSender,
-		To:       tx.To(),
-		GasPrice: tx.GasPrice(),
-		Gas:      tx.Gas(),
-		//GasTipCap: tx.GasTipCap(),
-		//GasFeeCap: tx.GasFeeCap(),
+		From:  executor.txSender,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
 		Value: tx.Value(),
 		Data:  tx.Data(),
 	}
+	if tx.Type() == types.DynamicFeeTxType {
+		msg.GasTipCap = tx.GasTipCap()
+		msg.GasFeeCap = tx.GasFeeCap()
+	} else {
+		msg.GasPrice = tx.GasPrice()
+	}
 	return executor.GetClient().CallContract(context.Background(), msg, blockNumber)
 }
 
@@ -365,11 +578,11 @@ func (executor *COREExecutor) GetRelayerBalance() (*big.Int, error) {
 	return executor.GetClient().BalanceAt(context.Background(), executor.txSender, nil)
 }
 
-func (executor *COREExecutor) CheckBlockRelayed(blockHash *chainhash.Hash) (bool, error) {
+func (executor *COREExecutor) CheckBlockRelayed(ctx context.Context, blockHash *chainhash.Hash) (bool, error) {
 
 	bHash := RevertHash(blockHash)
 
-	callOpts, err := executor.getCallOpts()
+	callOpts, err := executor.getCallOpts(ctx)
 	instance, err := cgccaller.NewCGCCaller(pcsAddr, executor.GetClient())
 	result, err := instance.IsHeaderSynced(callOpts, bHash)
 	//_, err := executor.CallContext(executor.GetClient(), result, context.Background(), "isHeaderSynced", blockHash)
@@ -377,31 +590,39 @@ func (executor *COREExecutor) CheckBlockRelayed(blockHash *chainhash.Hash) (bool
 	return result, err
 }
 
-func (executor *COREExecutor) QuerySubmitters(blockHash *chainhash.Hash) (string, error) {
+func (executor *COREExecutor) QuerySubmitters(ctx context.Context, blockHash *chainhash.Hash) (string, error) {
 
 	bHash := RevertHash(blockHash)
 
-	callOpts, err := executor.getCallOpts()
+	callOpts, err := executor.getCallOpts(ctx)
 	instance, err := cgccaller.NewCGCCaller(pcsAddr, executor.GetClient())
 	result, err := instance.QuerySubmitters(callOpts, bHash)
 
 	return result, err
 }
 
-func (executor *COREExecutor) syncBtcHeader(btcLightMirror *lightmirror.BtcLightMirrorV2, blockHash *chainhash.Hash) (common.Hash, error) {
-	nonce, err := executor.GetClient().PendingNonceAt(context.Background(), executor.txSender)
-	callOpts, err := executor.getTransactor(nonce)
-
+func (executor *COREExecutor) syncBtcHeader(ctx context.Context, bts []byte, blockHash *chainhash.Hash, gasLimit uint64) (*types.Transaction, error) {
 	instance, err := cgccaller.NewCGCCaller(pcsAddr, executor.GetClient())
+	if err != nil {
+		return nil, err
+	}
 
-	bts, err := serializeBtcLightMirror(btcLightMirror)
-	txHash, err := instance.SyncBtcHeader(callOpts, bts)
+	var tx *types.Transaction
+	_, err = executor.nonces.Send(ctx, func(nonce uint64) error {
+		txOpts, err := executor.getTransactor(ctx, nonce)
+		if err != nil {
+			return err
+		}
+		txOpts.GasLimit = gasLimit
+		tx, err = instance.StoreBlockHeader(txOpts, bts)
+		return err
+	})
 
 	if err != nil {
 		log.Println("sync btc header failed, hash:" + blockHash.String())
 	}
 
-	return txHash, err
+	return tx, err
 }
 
 // callContext
@@ -2,17 +2,23 @@ package executor
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
+	"errors"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/coredao-org/btcpowermirror/lightmirror"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 	"log"
+	"strings"
 	"testing"
 
 	relayercommon "github.com/coredao-org/btc-relayer/common"
 
 	config "github.com/coredao-org/btc-relayer/config"
+	cgccaller "github.com/coredao-org/btc-relayer/executor/cc"
 	"github.com/stretchr/testify/require"
 )
 
@@ -44,7 +50,7 @@ func TestCOREExecutor_NewBTCExecutor(t *testing.T) {
 }
 
 func TestCOREExecutor_GetLatestBlockHeight(t *testing.T) {
-	executor, err := NewCOREExecutor(cfg)
+	executor, err := NewCOREExecutor(nil, cfg)
 	require.NoError(t, err)
 
 	height, err := executor.GetLatestBlockHeight(executor.GetClient())
@@ -53,15 +59,15 @@ func TestCOREExecutor_GetLatestBlockHeight(t *testing.T) {
 }
 
 func TestCOREExecutor_UpdateClients(t *testing.T) {
-	executor, err := NewCOREExecutor(cfg)
+	executor, err := NewCOREExecutor(nil, cfg)
 	require.NoError(t, err)
 
-	executor.UpdateClients()
+	executor.UpdateClients(context.Background())
 	require.NoError(t, err)
 }
 
 func TestCOREExecutor_RegisterRelayer(t *testing.T) {
-	executor, err := NewCOREExecutor(cfg)
+	executor, err := NewCOREExecutor(nil, cfg)
 	require.NoError(t, err)
 
 	txHash, err := executor.RegisterRelayer()
@@ -70,7 +76,7 @@ func TestCOREExecutor_RegisterRelayer(t *testing.T) {
 }
 
 func TestCOREExecutor_IsRelayer(t *testing.T) {
-	executor, err := NewCOREExecutor(cfg)
+	executor, err := NewCOREExecutor(nil, cfg)
 	require.NoError(t, err)
 
 	isRelayer, err := executor.IsRelayer()
@@ -79,10 +85,10 @@ func TestCOREExecutor_IsRelayer(t *testing.T) {
 }
 
 func TestCOREExecutor_CheckBlockRelayed(t *testing.T) {
-	executor, err := NewCOREExecutor(cfg)
+	executor, err := NewCOREExecutor(nil, cfg)
 	require.NoError(t, err)
 
-	result, err := executor.CheckBlockRelayed(&chainhash.Hash{})
+	result, err := executor.CheckBlockRelayed(context.Background(), &chainhash.Hash{})
 	require.NoError(t, err)
 	require.Equal(t, false, result, "")
 }
@@ -90,7 +96,7 @@ func TestCOREExecutor_CheckBlockRelayed(t *testing.T) {
 func TestCOREExecutor_SyncBTCLightMirror(t *testing.T) {
 	BTCExecutor, err := NewBTCExecutor(cfg)
 	require.NoError(t, err)
-	executor, err := NewCOREExecutor(cfg)
+	executor, err := NewCOREExecutor(nil, cfg)
 	require.NoError(t, err)
 
 	hash, err := BTCExecutor.GetBlockHash(BTCExecutor.GetClient(), 717696)
@@ -100,7 +106,7 @@ func TestCOREExecutor_SyncBTCLightMirror(t *testing.T) {
 	require.NoError(t, err)
 
 	task := relayercommon.Task{BLOCK: block, BlockHash: hash, Height: 717696}
-	txHash, err := executor.SyncBTCLightMirror(&task)
+	txHash, err := executor.SyncBTCLightMirror(context.Background(), &task)
 	require.NoError(t, err)
 	t.Log(txHash.String())
 }
@@ -146,3 +152,52 @@ func printMirror(mirror *lightmirror.BtcLightMirrorV2) {
 	ss := hex.EncodeToString(bb)
 	log.Println(ss)
 }
+
+func TestCOREExecutor_LightClientChain(t *testing.T) {
+	eth, server := newFakeCoreNode(t)
+	defer server.Close()
+	executor := newTestCOREExecutor(t, server.URL, config.COREConfig{})
+
+	//the light client keys blocks by the hash in contract order
+	blockHash, err := chainhash.NewHashFromStr("00000000000000000002a7c4c1e48d76c5a37902165a270156b7a8d72728a054")
+	require.NoError(t, err)
+	prevHash, err := chainhash.NewHashFromStr("000000000000000000025e5b4e7b4e9c5d3f3bb1d7b1dbcc1a3e2f7a8f6b9c01")
+	require.NoError(t, err)
+	parsed, err := abi.JSON(strings.NewReader(cgccaller.CGCABI))
+	require.NoError(t, err)
+	eth.call = func(args map[string]interface{}, block string) (hexutil.Bytes, error) {
+		input, ok := args["input"].(string)
+		if !ok {
+			input, _ = args["data"].(string)
+		}
+		data, err := hexutil.Decode(input)
+		if err != nil || len(data) < 4 {
+			return nil, errors.New("invalid call data")
+		}
+		method, err := parsed.MethodById(data[:4])
+		if err != nil {
+			return nil, err
+		}
+		inputs, err := method.Inputs.Unpack(data[4:])
+		if err != nil {
+			return nil, err
+		}
+		if inputs[0] != [32]byte(*RevertHash(blockHash)) {
+			return nil, errors.New("unknown block")
+		}
+		switch method.Name {
+		case "getPrevHash":
+			return method.Outputs.Pack([32]byte(*RevertHash(prevHash)))
+		case "getHeight":
+			return method.Outputs.Pack(uint32(766000))
+		}
+		return nil, errors.New("unsupported method " + method.Name)
+	}
+
+	gotPrevHash, err := executor.GetPrevHash(context.Background(), blockHash)
+	require.NoError(t, err)
+	require.Equal(t, prevHash, gotPrevHash)
+	height, err := executor.GetHeight(context.Background(), blockHash)
+	require.NoError(t, err)
+	require.Equal(t, int64(766000), height)
+}
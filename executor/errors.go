@@ -0,0 +1,190 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	relayercommon "github.com/coredao-org/btc-relayer/common"
+	cgccaller "github.com/coredao-org/btc-relayer/executor/cc"
+)
+
+var (
+	// ErrSimulationReverted means storeBlockHeader reverted in simulation, sending it would fail the same way
+	ErrSimulationReverted = errors.New("simulation reverted")
+
+	// ErrTxDropped means the relay tx left the mempool without being included
+	ErrTxDropped = errors.New("relay tx dropped")
+	// ErrFeeCeiling means a stuck relay tx can not be replaced without exceeding max_gas_price or max_fee_per_gas
+	ErrFeeCeiling = errors.New("fee ceiling reached")
+	// ErrReceiptTimeout means the relay tx got no outcome within confirm_timeout_second
+	ErrReceiptTimeout = errors.New("relay tx receipt timeout")
+
+	// errors of the light client, see the ERR_* constants of the contract
+	ErrBlockAlreadyExists = errors.New("block already exists")
+	ErrNoPrevBlock        = errors.New("previous block not found")
+	ErrMerkle             = errors.New("merkle proof failed")
+	ErrProofOfWork        = errors.New("proof of work failed")
+	ErrRetarget           = errors.New("difficulty retarget failed")
+	ErrDifficulty         = errors.New("difficulty mismatch")
+	ErrRelayFailed        = errors.New("relay failed")
+
+	// errors of the local header validation only, the others are shared with the light client
+	ErrPrevBlockMismatch = errors.New("previous block mismatch")
+	ErrTimestamp         = errors.New("timestamp not after median time past")
+)
+
+// light client error constants, and their values in the deployed contract
+var lightClientErrors = []struct {
+	constant string
+	code     int64
+	err      error
+}{
+	{"ERR_DIFFICULTY", 10010, ErrDifficulty},
+	{"ERR_RETARGET", 10020, ErrRetarget},
+	{"ERR_NO_PREV_BLOCK", 10030, ErrNoPrevBlock},
+	{"ERR_BLOCK_ALREADY_EXISTS", 10040, ErrBlockAlreadyExists},
+	{"ERR_MERKLE", 10050, ErrMerkle},
+	{"ERR_PROOF_OF_WORK", 10090, ErrProofOfWork},
+}
+
+// revert reason keywords, retarget is checked before difficulty
+var revertReasonErrors = []struct {
+	keywords []string
+	err      error
+}{
+	{[]string{"already exist", "ERR_BLOCK_ALREADY_EXISTS"}, ErrBlockAlreadyExists},
+	{[]string{"prev", "ERR_NO_PREV_BLOCK"}, ErrNoPrevBlock},
+	{[]string{"merkle", "ERR_MERKLE"}, ErrMerkle},
+	{[]string{"proof of work", "proof-of-work", "ERR_PROOF_OF_WORK"}, ErrProofOfWork},
+	{[]string{"retarget", "ERR_RETARGET"}, ErrRetarget},
+	{[]string{"difficulty", "ERR_DIFFICULTY"}, ErrDifficulty},
+}
+
+// RelayError is a storeBlockHeader that failed, with the light client return code or revert reason
+type RelayError struct {
+	BlockHash  string
+	TxHash     common.Hash
+	ReturnCode int64
+	Reason     string
+	// Simulated is set if the failure was found by simulation, nothing was sent
+	Simulated bool
+	Err       error
+}
+
+func (e *RelayError) Error() string {
+	msg := fmt.Sprintf("relay block %s failed: %s", e.BlockHash, e.Err.Error())
+	if e.TxHash != (common.Hash{}) {
+		msg += ", txHash:" + e.TxHash.String()
+	}
+	if e.ReturnCode != 0 {
+		msg += fmt.Sprintf(", returnCode:%d", e.ReturnCode)
+	}
+	if e.Reason != "" {
+		msg += ", reason:" + e.Reason
+	}
+	return msg
+}
+
+func (e *RelayError) Unwrap() error {
+	return e.Err
+}
+
+func (e *RelayError) Is(target error) bool {
+	return target == ErrSimulationReverted && e.Simulated
+}
+
+// HeaderError is a header failing the local validation, it is not relayed
+type HeaderError struct {
+	Height    int64
+	BlockHash string
+	Provider  string
+	Err       error
+}
+
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("header of block %s at height %d from %s is invalid: %s", e.BlockHash, e.Height, e.Provider, e.Err.Error())
+}
+
+func (e *HeaderError) Unwrap() error {
+	return e.Err
+}
+
+/**
+map a light client return code to its error, codes are read from the contract if possible
+*/
+func (executor *COREExecutor) errorOfReturnCode(code int64) error {
+	executor.returnCodesOnce.Do(executor.loadReturnCodes)
+	if err, ok := executor.returnCodes[code]; ok {
+		return err
+	}
+	return ErrRelayFailed
+}
+
+func (executor *COREExecutor) loadReturnCodes() {
+	returnCodes := make(map[int64]error)
+	for _, lcErr := range lightClientErrors {
+		returnCodes[lcErr.code] = lcErr.err
+	}
+
+	instance, err := cgccaller.NewCGCCaller(pcsAddr, executor.GetClient())
+	if err == nil {
+		callOpts, _ := executor.getCallOpts(context.Background())
+		for _, lcErr := range lightClientErrors {
+			code, err := instance.GetInt256Constant(callOpts, lcErr.constant)
+			if err != nil {
+				relayercommon.Logger.Warningf("query %s error, use default %d, err=%s", lcErr.constant, lcErr.code, err.Error())
+				continue
+			}
+			if code.Int64() != lcErr.code {
+				delete(returnCodes, lcErr.code)
+				returnCodes[code.Int64()] = lcErr.err
+			}
+		}
+	}
+	executor.returnCodes = returnCodes
+}
+
+/**
+map a revert reason of storeBlockHeader to a light client error
+*/
+func errorOfRevertReason(reason string) error {
+	lower := strings.ToLower(reason)
+	for _, rrErr := range revertReasonErrors {
+		for _, keyword := range rrErr.keywords {
+			if strings.Contains(lower, strings.ToLower(keyword)) {
+				return rrErr.err
+			}
+		}
+	}
+	return ErrRelayFailed
+}
+
+/**
+extract the revert reason from the error of eth_call or eth_estimateGas
+*/
+func revertReason(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if data, ok := dataErr.ErrorData().(string); ok {
+			if bz, decodeErr := hexutil.Decode(data); decodeErr == nil {
+				if reason, unpackErr := abi.UnpackRevert(bz); unpackErr == nil {
+					return reason, true
+				}
+			}
+		}
+	}
+	if strings.Contains(err.Error(), "execution reverted") {
+		return strings.TrimPrefix(strings.TrimPrefix(err.Error(), "execution reverted"), ": "), true
+	}
+	return "", false
+}
@@ -0,0 +1,120 @@
+package executor
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/coredao-org/btc-relayer/config"
+	cgccaller "github.com/coredao-org/btc-relayer/executor/cc"
+)
+
+const testBtcBlockHash = "00000000000000000001ad3a9d9a8c0d3a0e4b3b8e3f1f2a4c6d7e8f9a0b1c2d"
+
+func storeHeaderLog(blockHash common.Hash, returnCode int64) *types.Log {
+	return &types.Log{
+		Address: pcsAddr,
+		Topics: []common.Hash{
+			cgccaller.StoreHeaderEventID,
+			blockHash,
+			common.BigToHash(math.U256(big.NewInt(returnCode))),
+		},
+	}
+}
+
+func TestErrorOfRevertReason(t *testing.T) {
+	require.Equal(t, ErrBlockAlreadyExists, errorOfRevertReason("block already exists"))
+	require.Equal(t, ErrNoPrevBlock, errorOfRevertReason("can not find previous block"))
+	require.Equal(t, ErrMerkle, errorOfRevertReason("ERR_MERKLE"))
+	require.Equal(t, ErrProofOfWork, errorOfRevertReason("proof of work check failed"))
+	require.Equal(t, ErrRetarget, errorOfRevertReason("invalid retarget difficulty"))
+	require.Equal(t, ErrDifficulty, errorOfRevertReason("difficulty mismatch"))
+	require.Equal(t, ErrRelayFailed, errorOfRevertReason("not a relayer"))
+}
+
+func TestParseStoreHeader(t *testing.T) {
+	blockHash := common.HexToHash("0x1234")
+
+	event, ok := cgccaller.ParseStoreHeader(*storeHeaderLog(blockHash, 10030))
+	require.True(t, ok)
+	require.Equal(t, blockHash, event.BlockHash)
+	require.Equal(t, int64(10030), event.ReturnCode.Int64())
+
+	// negative codes are sign extended int256
+	event, ok = cgccaller.ParseStoreHeader(*storeHeaderLog(blockHash, -1))
+	require.True(t, ok)
+	require.Equal(t, int64(-1), event.ReturnCode.Int64())
+
+	_, ok = cgccaller.ParseStoreHeader(types.Log{Topics: []common.Hash{common.HexToHash("0x01")}})
+	require.False(t, ok)
+}
+
+func TestRelayError(t *testing.T) {
+	err := error(&RelayError{BlockHash: testBtcBlockHash, Reason: "can not find previous block", Simulated: true, Err: ErrNoPrevBlock})
+	require.True(t, errors.Is(err, ErrNoPrevBlock))
+	require.True(t, errors.Is(err, ErrSimulationReverted))
+	require.False(t, errors.Is(err, ErrMerkle))
+
+	err = &RelayError{BlockHash: testBtcBlockHash, ReturnCode: 10050, Err: ErrMerkle}
+	require.True(t, errors.Is(err, ErrMerkle))
+	require.False(t, errors.Is(err, ErrSimulationReverted))
+	require.Contains(t, err.Error(), "returnCode:10050")
+}
+
+func TestRelayErrorOfReceipt(t *testing.T) {
+	_, server := newFakeCoreNode(t)
+	defer server.Close()
+	executor := newTestCOREExecutor(t, server.URL, config.COREConfig{GasLimit: 4700000})
+
+	btcHash, err := chainhash.NewHashFromStr(testBtcBlockHash)
+	require.NoError(t, err)
+	bHash := common.Hash(*RevertHash(btcHash))
+
+	// the node can not serve the ERR_* constants, the default codes are used
+	receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{storeHeaderLog(bHash, 10040)}}
+	relayErr := executor.relayErrorOfReceipt(btcHash, receipt)
+	require.NotNil(t, relayErr)
+	require.True(t, errors.Is(relayErr, ErrBlockAlreadyExists))
+	require.Equal(t, int64(10040), relayErr.ReturnCode)
+
+	// events of other blocks are ignored
+	receipt.Logs = []*types.Log{storeHeaderLog(common.HexToHash("0x01"), 10040), storeHeaderLog(bHash, 0)}
+	require.Nil(t, executor.relayErrorOfReceipt(btcHash, receipt))
+
+	receipt.Logs = []*types.Log{storeHeaderLog(bHash, 12345)}
+	require.True(t, errors.Is(executor.relayErrorOfReceipt(btcHash, receipt), ErrRelayFailed))
+}
+
+func TestRelayErrorOfRevert(t *testing.T) {
+	eth, server := newFakeCoreNode(t)
+	defer server.Close()
+	executor := newTestCOREExecutor(t, server.URL, config.COREConfig{GasLimit: 4700000})
+
+	btcHash, err := chainhash.NewHashFromStr(testBtcBlockHash)
+	require.NoError(t, err)
+
+	// replayed on the parent block
+	eth.call = func(args map[string]interface{}, block string) (hexutil.Bytes, error) {
+		require.Equal(t, "0x63", block)
+		return nil, &revertError{reason: "proof of work check failed"}
+	}
+	tx := newTestTx()
+	relayErr := executor.relayErrorOfRevert(btcHash, tx, &types.Receipt{Status: types.ReceiptStatusFailed, BlockNumber: big.NewInt(100)})
+	require.True(t, errors.Is(relayErr, ErrProofOfWork))
+	require.Equal(t, tx.Hash(), relayErr.TxHash)
+	require.Equal(t, "proof of work check failed", relayErr.Reason)
+
+	// no revert reason
+	eth.call = func(args map[string]interface{}, block string) (hexutil.Bytes, error) {
+		return hexutil.Bytes{}, nil
+	}
+	relayErr = executor.relayErrorOfRevert(btcHash, tx, &types.Receipt{Status: types.ReceiptStatusFailed, BlockNumber: big.NewInt(100)})
+	require.True(t, errors.Is(relayErr, ErrRelayFailed))
+}
@@ -0,0 +1,138 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// EsploraTimeout is the timeout of a request to an Esplora REST API
+const EsploraTimeout = 30 * time.Second
+
+// esploraSource is an Esplora (or Electrs) REST API, e.g. https://blockstream.info/api
+type esploraSource struct {
+	baseURL string
+	client  *http.Client
+}
+
+func newEsploraSource(baseURL string) *esploraSource {
+	return &esploraSource{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: EsploraTimeout},
+	}
+}
+
+func (source *esploraSource) get(path string) ([]byte, error) {
+	resp, err := source.client.Get(source.baseURL + path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("esplora GET %s: %s %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+func (source *esploraSource) getText(path string) (string, error) {
+	body, err := source.get(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+func (source *esploraSource) GetBlockCount() (int64, error) {
+	height, err := source.getText("/blocks/tip/height")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(height, 10, 64)
+}
+
+func (source *esploraSource) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	hash, err := source.getText("/block-height/" + strconv.FormatInt(height, 10))
+	if err != nil {
+		return nil, err
+	}
+	return chainhash.NewHashFromStr(hash)
+}
+
+func (source *esploraSource) GetBlockHeader(hash *chainhash.Hash) (*wire.BlockHeader, error) {
+	headerHex, err := source.getText("/block/" + hash.String() + "/header")
+	if err != nil {
+		return nil, err
+	}
+	bz, err := hex.DecodeString(headerHex)
+	if err != nil {
+		return nil, err
+	}
+	var header wire.BlockHeader
+	if err := header.Deserialize(bytes.NewReader(bz)); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+func (source *esploraSource) GetBlockHeight(hash *chainhash.Hash) (int64, error) {
+	body, err := source.get("/block/" + hash.String())
+	if err != nil {
+		return 0, err
+	}
+	var block struct {
+		Height int64 `json:"height"`
+	}
+	if err := json.Unmarshal(body, &block); err != nil {
+		return 0, err
+	}
+	return block.Height, nil
+}
+
+func (source *esploraSource) GetBlockTxHashes(hash *chainhash.Hash) ([]chainhash.Hash, error) {
+	body, err := source.get("/block/" + hash.String() + "/txids")
+	if err != nil {
+		return nil, err
+	}
+	var txids []string
+	if err := json.Unmarshal(body, &txids); err != nil {
+		return nil, err
+	}
+	return parseTxHashes(txids)
+}
+
+/**
+esplora indexes all txs, the block hash is not needed
+*/
+func (source *esploraSource) GetRawTransaction(txHash *chainhash.Hash, blockHash *chainhash.Hash) (*wire.MsgTx, error) {
+	txHex, err := source.getText("/tx/" + txHash.String() + "/hex")
+	if err != nil {
+		return nil, err
+	}
+	return deserializeTx(txHex)
+}
+
+func (source *esploraSource) GetBlock(hash *chainhash.Hash) (*wire.MsgBlock, error) {
+	body, err := source.get("/block/" + hash.String() + "/raw")
+	if err != nil {
+		return nil, err
+	}
+	var block wire.MsgBlock
+	if err := block.Deserialize(bytes.NewReader(body)); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
@@ -0,0 +1,172 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+// fakeEsplora serves the Esplora REST API of a chain of blocks, the block at index i has height i
+func newFakeEsplora(t *testing.T, blocks []*wire.MsgBlock) *httptest.Server {
+	byHash := make(map[string]int)
+	for height, block := range blocks {
+		byHash[block.BlockHash().String()] = height
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+		write := func(body interface{}) {
+			switch body := body.(type) {
+			case string:
+				w.Write([]byte(body))
+			case []byte:
+				w.Write(body)
+			default:
+				require.NoError(t, json.NewEncoder(w).Encode(body))
+			}
+		}
+		notFound := func() {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("Block not found"))
+		}
+
+		switch {
+		case len(path) == 3 && path[0] == "blocks" && path[1] == "tip" && path[2] == "height":
+			write(strconv.Itoa(len(blocks) - 1))
+		case len(path) == 2 && path[0] == "block-height":
+			height, err := strconv.Atoi(path[1])
+			if err != nil || height >= len(blocks) {
+				notFound()
+				return
+			}
+			write(blocks[height].BlockHash().String())
+		case len(path) >= 2 && path[0] == "block":
+			height, ok := byHash[path[1]]
+			if !ok {
+				notFound()
+				return
+			}
+			block := blocks[height]
+			var buf bytes.Buffer
+			switch {
+			case len(path) == 2:
+				write(map[string]interface{}{"id": path[1], "height": height, "tx_count": len(block.Transactions)})
+			case path[2] == "header":
+				require.NoError(t, block.Header.Serialize(&buf))
+				write(hex.EncodeToString(buf.Bytes()))
+			case path[2] == "txids":
+				txids := make([]string, 0, len(block.Transactions))
+				for _, tx := range block.Transactions {
+					txids = append(txids, tx.TxHash().String())
+				}
+				write(txids)
+			case path[2] == "raw":
+				require.NoError(t, block.Serialize(&buf))
+				write(buf.Bytes())
+			default:
+				notFound()
+			}
+		case len(path) == 3 && path[0] == "tx" && path[2] == "hex":
+			for _, block := range blocks {
+				for _, tx := range block.Transactions {
+					if tx.TxHash().String() == path[1] {
+						var buf bytes.Buffer
+						require.NoError(t, tx.Serialize(&buf))
+						write(hex.EncodeToString(buf.Bytes()))
+						return
+					}
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("Transaction not found"))
+		default:
+			notFound()
+		}
+	}))
+}
+
+func newTestEsploraChain(t *testing.T) []*wire.MsgBlock {
+	genesis := newTestBlock(t)
+	next := newTestBlock(t)
+	next.Header.PrevBlock = genesis.BlockHash()
+	return []*wire.MsgBlock{genesis, next}
+}
+
+func TestEsploraSource(t *testing.T) {
+	blocks := newTestEsploraChain(t)
+	server := newFakeEsplora(t, blocks)
+	defer server.Close()
+	// a trailing slash is fine
+	source := newEsploraSource(server.URL + "/")
+
+	height, err := source.GetBlockCount()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), height)
+
+	blockHash, err := source.GetBlockHash(1)
+	require.NoError(t, err)
+	require.Equal(t, blocks[1].BlockHash(), *blockHash)
+
+	header, err := source.GetBlockHeader(blockHash)
+	require.NoError(t, err)
+	require.Equal(t, blocks[1].Header, *header)
+
+	height, err = source.GetBlockHeight(blockHash)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), height)
+
+	txHashes, err := source.GetBlockTxHashes(blockHash)
+	require.NoError(t, err)
+	require.Equal(t, fillTxHashes(blocks[1].Transactions), txHashes)
+
+	coinbase, err := source.GetRawTransaction(&txHashes[0], blockHash)
+	require.NoError(t, err)
+	require.Equal(t, txHashes[0], coinbase.TxHash())
+	require.Equal(t, blocks[1].Transactions[0].WitnessHash(), coinbase.WitnessHash())
+
+	block, err := source.GetBlock(blockHash)
+	require.NoError(t, err)
+	require.Equal(t, blocks[1].BlockHash(), block.BlockHash())
+	require.Equal(t, txHashes, fillTxHashes(block.Transactions))
+
+	_, err = source.GetBlockHash(2)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "404")
+	_, err = source.GetBlockHeader(&chainhash.Hash{})
+	require.Error(t, err)
+}
+
+func TestBTCExecutor_EsploraSource(t *testing.T) {
+	blocks := newTestEsploraChain(t)
+	server := newFakeEsplora(t, blocks)
+	defer server.Close()
+	executor, err := NewBTCExecutor(&config.Config{BTCConfig: config.BTCConfig{
+		RpcAddrs: []config.BTCRpcAddrs{{Source: config.BTCSourceEsplora, Host: server.URL}},
+	}})
+	require.NoError(t, err)
+
+	height, err := executor.GetLatestBlockHeight(executor.GetClient())
+	require.NoError(t, err)
+	blockHash, err := executor.GetBlockHash(executor.GetClient(), height)
+	require.NoError(t, err)
+
+	task, err := executor.GetRelayTask(executor.GetClient(), height, blockHash)
+	require.NoError(t, err)
+	require.NotNil(t, task.PARTS)
+	fromParts, err := serializeBtcLightMirror(NewBtcLightMirrorFromParts(task.PARTS))
+	require.NoError(t, err)
+	fromBlock, err := serializeBtcLightMirror(NewBtcLightMirror(blocks[height]))
+	require.NoError(t, err)
+	require.Equal(t, fromBlock, fromParts)
+}
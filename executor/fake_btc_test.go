@@ -0,0 +1,156 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+// fakeBitcoind serves the json-rpc of a bitcoind, only what the executor uses
+type fakeBitcoind struct {
+	mu      sync.Mutex
+	blocks  map[chainhash.Hash]*wire.MsgBlock
+	heights map[chainhash.Hash]int32
+	// methods counts the calls of each method
+	methods     map[string]int
+	unsupported map[string]bool
+	// tamper changes the served txids and blocks, like a misbehaving or lagging provider
+	tamper func(block *wire.MsgBlock) *wire.MsgBlock
+}
+
+type btcRequest struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	ID     json.RawMessage   `json:"id"`
+}
+
+type btcResponse struct {
+	Result interface{}       `json:"result"`
+	Error  *btcjson.RPCError `json:"error"`
+	ID     json.RawMessage   `json:"id"`
+}
+
+func newFakeBitcoind(t *testing.T) (*fakeBitcoind, *httptest.Server) {
+	bitcoind := &fakeBitcoind{
+		blocks:      make(map[chainhash.Hash]*wire.MsgBlock),
+		heights:     make(map[chainhash.Hash]int32),
+		methods:     make(map[string]int),
+		unsupported: make(map[string]bool),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var request btcRequest
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&request))
+		result, rpcErr := bitcoind.serve(request)
+		require.NoError(t, json.NewEncoder(w).Encode(&btcResponse{Result: result, Error: rpcErr, ID: request.ID}))
+	}))
+	return bitcoind, server
+}
+
+func (bitcoind *fakeBitcoind) addBlock(block *wire.MsgBlock, height int32) {
+	bitcoind.mu.Lock()
+	defer bitcoind.mu.Unlock()
+	bitcoind.blocks[block.BlockHash()] = block
+	bitcoind.heights[block.BlockHash()] = height
+}
+
+func (bitcoind *fakeBitcoind) calls(method string) int {
+	bitcoind.mu.Lock()
+	defer bitcoind.mu.Unlock()
+	return bitcoind.methods[method]
+}
+
+func (bitcoind *fakeBitcoind) serve(request btcRequest) (interface{}, *btcjson.RPCError) {
+	bitcoind.mu.Lock()
+	defer bitcoind.mu.Unlock()
+	bitcoind.methods[request.Method]++
+	if bitcoind.unsupported[request.Method] {
+		return nil, btcjson.ErrRPCMethodNotFound
+	}
+
+	var params []interface{}
+	for _, param := range request.Params {
+		var value interface{}
+		json.Unmarshal(param, &value)
+		params = append(params, value)
+	}
+	if len(params) == 0 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "missing params")
+	}
+	//getrawtransaction takes the block hash last
+	blockParam := params[0]
+	if request.Method == "getrawtransaction" {
+		blockParam = params[len(params)-1]
+	}
+	block, rpcErr := bitcoind.block(blockParam)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	if bitcoind.tamper != nil && request.Method == "getblock" {
+		block = bitcoind.tamper(block)
+	}
+
+	switch request.Method {
+	case "getblockheader":
+		if len(params) > 1 && params[1] == true {
+			return &btcjson.GetBlockHeaderVerboseResult{Hash: block.BlockHash().String(), Height: bitcoind.heights[block.BlockHash()]}, nil
+		}
+		var buf bytes.Buffer
+		block.Header.Serialize(&buf)
+		return hex.EncodeToString(buf.Bytes()), nil
+	case "getblock":
+		if len(params) > 1 && params[1] != float64(0) {
+			txids := make([]string, 0, len(block.Transactions))
+			for _, tx := range block.Transactions {
+				txids = append(txids, tx.TxHash().String())
+			}
+			return &btcjson.GetBlockVerboseResult{Hash: block.BlockHash().String(), Tx: txids}, nil
+		}
+		var buf bytes.Buffer
+		block.Serialize(&buf)
+		return hex.EncodeToString(buf.Bytes()), nil
+	case "getrawtransaction":
+		//only the txs of the block passed, as without txindex
+		for _, tx := range block.Transactions {
+			if tx.TxHash().String() == params[0] {
+				var buf bytes.Buffer
+				tx.Serialize(&buf)
+				return hex.EncodeToString(buf.Bytes()), nil
+			}
+		}
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "No such transaction found in the provided block")
+	}
+	return nil, btcjson.ErrRPCMethodNotFound
+}
+
+func (bitcoind *fakeBitcoind) block(param interface{}) (*wire.MsgBlock, *btcjson.RPCError) {
+	hash, err := chainhash.NewHashFromStr(fmt.Sprint(param))
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, err.Error())
+	}
+	block, ok := bitcoind.blocks[*hash]
+	if !ok {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCBlockNotFound, "Block not found")
+	}
+	return block, nil
+}
+
+func newTestBTCExecutor(t *testing.T, url string) *BTCExecutor {
+	executor, err := NewBTCExecutor(&config.Config{BTCConfig: config.BTCConfig{
+		RpcAddrs: []config.BTCRpcAddrs{{Host: strings.TrimPrefix(url, "http://"), User: "user", Pass: "pass"}},
+	}})
+	require.NoError(t, err)
+	return executor
+}
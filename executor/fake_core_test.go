@@ -0,0 +1,240 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/coredao-org/btc-relayer/config"
+	cgccaller "github.com/coredao-org/btc-relayer/executor/cc"
+)
+
+// testCoreGenesisTime is the time of block 0 of the fake Core node
+const testCoreGenesisTime = 1700000000
+
+// revertError is how a node reports a reverted eth_call or eth_estimateGas
+type revertError struct {
+	reason string
+}
+
+func (e *revertError) Error() string {
+	return "execution reverted: " + e.reason
+}
+
+func (e *revertError) ErrorCode() int {
+	return 3
+}
+
+func (e *revertError) ErrorData() interface{} {
+	stringType, _ := abi.NewType("string", "", nil)
+	packed, _ := abi.Arguments{{Type: stringType}}.Pack(e.reason)
+	return hexutil.Encode(append(crypto.Keccak256([]byte("Error(string)"))[:4], packed...))
+}
+
+// fakeEth serves the eth namespace of a Core node, only what the executor uses
+type fakeEth struct {
+	mu          sync.Mutex
+	chainID     *big.Int
+	estimateGas func(args map[string]interface{}) (hexutil.Uint64, error)
+	estimates   int
+	call        func(args map[string]interface{}, block string) (hexutil.Bytes, error)
+
+	head     uint64
+	baseFee  *big.Int
+	nonce    uint64
+	txs      map[common.Hash]*types.Transaction
+	receipts map[common.Hash]*types.Receipt
+	sent     []*types.Transaction
+	onSend   func(tx *types.Transaction)
+}
+
+func (eth *fakeEth) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(eth.chainID)
+}
+
+func (eth *fakeEth) EstimateGas(ctx context.Context, args map[string]interface{}) (hexutil.Uint64, error) {
+	eth.mu.Lock()
+	defer eth.mu.Unlock()
+	eth.estimates++
+	return eth.estimateGas(args)
+}
+
+func (eth *fakeEth) Call(ctx context.Context, args map[string]interface{}, block string) (hexutil.Bytes, error) {
+	if eth.call == nil {
+		return nil, errors.New("eth_call not supported")
+	}
+	return eth.call(args, block)
+}
+
+func (eth *fakeEth) BlockNumber() hexutil.Uint64 {
+	eth.mu.Lock()
+	defer eth.mu.Unlock()
+	return hexutil.Uint64(eth.head)
+}
+
+// GetBlockByNumber returns the header of a block, blocks are 3 seconds apart
+func (eth *fakeEth) GetBlockByNumber(number string, full bool) (*types.Header, error) {
+	eth.mu.Lock()
+	defer eth.mu.Unlock()
+	height := eth.head
+	if number != "latest" && number != "pending" {
+		var err error
+		if height, err = hexutil.DecodeUint64(number); err != nil {
+			return nil, err
+		}
+	}
+	return &types.Header{
+		Number:     new(big.Int).SetUint64(height),
+		Difficulty: big.NewInt(0),
+		Time:       testCoreGenesisTime + 3*height,
+		BaseFee:    eth.baseFee,
+	}, nil
+}
+
+func (eth *fakeEth) GetTransactionCount(address common.Address, block string) hexutil.Uint64 {
+	eth.mu.Lock()
+	defer eth.mu.Unlock()
+	return hexutil.Uint64(eth.nonce)
+}
+
+func (eth *fakeEth) GetTransactionByHash(hash common.Hash) (json.RawMessage, error) {
+	eth.mu.Lock()
+	defer eth.mu.Unlock()
+	tx, ok := eth.txs[hash]
+	if !ok {
+		return json.RawMessage("null"), nil
+	}
+	return tx.MarshalJSON()
+}
+
+func (eth *fakeEth) GetTransactionReceipt(hash common.Hash) *types.Receipt {
+	eth.mu.Lock()
+	defer eth.mu.Unlock()
+	return eth.receipts[hash]
+}
+
+func (eth *fakeEth) SendRawTransaction(input hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	eth.mu.Lock()
+	eth.sent = append(eth.sent, tx)
+	eth.txs[tx.Hash()] = tx
+	onSend := eth.onSend
+	eth.mu.Unlock()
+
+	if onSend != nil {
+		onSend(tx)
+	}
+	return tx.Hash(), nil
+}
+
+func (eth *fakeEth) setHead(head uint64) {
+	eth.mu.Lock()
+	defer eth.mu.Unlock()
+	eth.head = head
+}
+
+// forget makes the tx of hash unknown to the node, like a tx that left the mempool
+func (eth *fakeEth) forget(hash common.Hash) {
+	eth.mu.Lock()
+	defer eth.mu.Unlock()
+	delete(eth.txs, hash)
+	delete(eth.receipts, hash)
+}
+
+// setTx makes tx known to the node, with its receipt if it is included
+func (eth *fakeEth) setTx(tx *types.Transaction, receipt *types.Receipt) {
+	eth.mu.Lock()
+	defer eth.mu.Unlock()
+	if tx == nil {
+		return
+	}
+	eth.txs[tx.Hash()] = tx
+	if receipt == nil {
+		delete(eth.receipts, tx.Hash())
+		return
+	}
+	receipt.TxHash = tx.Hash()
+	if receipt.Logs == nil {
+		receipt.Logs = []*types.Log{}
+	}
+	eth.receipts[tx.Hash()] = receipt
+}
+
+// lightClientCall answers isHeaderSynced and submitters of the light client
+func lightClientCall(t *testing.T, relayed bool, submitter common.Address) func(args map[string]interface{}, block string) (hexutil.Bytes, error) {
+	parsed, err := abi.JSON(strings.NewReader(cgccaller.CGCABI))
+	require.NoError(t, err)
+	return func(args map[string]interface{}, block string) (hexutil.Bytes, error) {
+		input, ok := args["input"].(string)
+		if !ok {
+			input, _ = args["data"].(string)
+		}
+		data, err := hexutil.Decode(input)
+		if err != nil || len(data) < 4 {
+			return nil, errors.New("invalid call data")
+		}
+		method, err := parsed.MethodById(data[:4])
+		if err != nil {
+			return nil, err
+		}
+		switch method.Name {
+		case "isHeaderSynced":
+			return method.Outputs.Pack(relayed)
+		case "submitters":
+			return method.Outputs.Pack(submitter)
+		case "storeBlockHeader":
+			return method.Outputs.Pack(uint32(0))
+		}
+		return nil, errors.New("unsupported method " + method.Name)
+	}
+}
+
+func newFakeCoreNode(t *testing.T) (*fakeEth, *httptest.Server) {
+	eth := &fakeEth{
+		chainID: big.NewInt(1116),
+		estimateGas: func(args map[string]interface{}) (hexutil.Uint64, error) {
+			return 100000, nil
+		},
+		txs:      make(map[common.Hash]*types.Transaction),
+		receipts: make(map[common.Hash]*types.Receipt),
+	}
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", eth))
+	return eth, httptest.NewServer(server)
+}
+
+// newTestCOREExecutor creates an executor talking to url and signing with testPrivateKey
+func newTestCOREExecutor(t *testing.T, url string, coreCfg config.COREConfig) *COREExecutor {
+	privKey, err := crypto.HexToECDSA(testPrivateKey)
+	require.NoError(t, err)
+	client, err := ethclient.Dial(url)
+	require.NoError(t, err)
+
+	signer := NewLocalSigner(privKey)
+	executor := &COREExecutor{
+		coreClients: []*COREClient{{COREClient: client, Provider: url, UpdatedAt: time.Now()}},
+		signer:      signer,
+		txSender:    signer.Address(),
+		cfg:         &config.Config{COREConfig: coreCfg},
+	}
+	executor.nonces = newNonceManager(executor.pendingNonce)
+	return executor
+}
@@ -0,0 +1,183 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+// FeeDecision records how the fee of a transaction was chosen
+type FeeDecision struct {
+	Mode string
+	// legacy mode
+	GasPrice *big.Int
+	// dynamic mode
+	BaseFee      *big.Int
+	SuggestedTip *big.Int
+	GasTipCap    *big.Int
+	GasFeeCap    *big.Int
+	Capped       bool
+}
+
+func (decision *FeeDecision) String() string {
+	if decision.Mode == config.FeeModeDynamic {
+		return fmt.Sprintf("mode=%s, baseFee=%s, suggestedTip=%s, tipCap=%s, feeCap=%s, capped=%t",
+			decision.Mode, decision.BaseFee, decision.SuggestedTip, decision.GasTipCap, decision.GasFeeCap, decision.Capped)
+	}
+	return fmt.Sprintf("mode=%s, gasPrice=%s", decision.Mode, decision.GasPrice)
+}
+
+// Apply sets the fee fields of txOpts, bind builds a DynamicFeeTx if GasPrice is nil
+func (decision *FeeDecision) Apply(txOpts *bind.TransactOpts) {
+	if decision.Mode == config.FeeModeDynamic {
+		txOpts.GasPrice = nil
+		txOpts.GasTipCap = decision.GasTipCap
+		txOpts.GasFeeCap = decision.GasFeeCap
+		return
+	}
+	txOpts.GasPrice = decision.GasPrice
+	txOpts.GasTipCap = nil
+	txOpts.GasFeeCap = nil
+}
+
+/**
+decide the fee of the next transaction according to fee_mode
+*/
+func (executor *COREExecutor) decideFee(ctx context.Context) (*FeeDecision, error) {
+	coreCfg := &executor.cfg.COREConfig
+	if coreCfg.FeeMode != config.FeeModeDynamic {
+		gasPrice := big.NewInt(DefaultGasPrice)
+		if coreCfg.GasPrice != 0 {
+			gasPrice = new(big.Int).SetUint64(coreCfg.GasPrice)
+		}
+		return &FeeDecision{Mode: config.FeeModeLegacy, GasPrice: gasPrice}, nil
+	}
+
+	client := executor.GetClient()
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if header.BaseFee == nil {
+		return nil, fmt.Errorf("core chain has no base fee at block %s, use fee_mode %s", header.Number, config.FeeModeLegacy)
+	}
+	suggestedTip, err := client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return computeDynamicFee(header.BaseFee, suggestedTip, coreCfg.GetTipMultiplier(), new(big.Int).SetUint64(coreCfg.MaxFeePerGas)), nil
+}
+
+/**
+tip is the suggested tip scaled by tipMultiplier, fee cap leaves room for the base fee to double,
+both are capped at maxFeePerGas
+*/
+func computeDynamicFee(baseFee *big.Int, suggestedTip *big.Int, tipMultiplier float64, maxFeePerGas *big.Int) *FeeDecision {
+	tip, _ := new(big.Float).Mul(new(big.Float).SetInt(suggestedTip), big.NewFloat(tipMultiplier)).Int(nil)
+	feeCap := new(big.Int).Add(new(big.Int).Mul(baseFee, big.NewInt(2)), tip)
+
+	decision := &FeeDecision{
+		Mode:         config.FeeModeDynamic,
+		BaseFee:      baseFee,
+		SuggestedTip: suggestedTip,
+	}
+	if maxFeePerGas.Sign() > 0 && feeCap.Cmp(maxFeePerGas) > 0 {
+		feeCap = new(big.Int).Set(maxFeePerGas)
+		decision.Capped = true
+	}
+	if tip.Cmp(feeCap) > 0 {
+		tip = new(big.Int).Set(feeCap)
+	}
+	decision.GasTipCap = tip
+	decision.GasFeeCap = feeCap
+	return decision
+}
+
+/**
+bump the fee of tx by bumpPercent for a replacement with the same nonce, capped at maxGasPrice or maxFeePerGas.
+Returns ErrFeeCeiling if the capped fee is not enough for the node to accept the replacement
+*/
+func bumpTxFee(tx *types.Transaction, bumpPercent uint64, maxGasPrice *big.Int, maxFeePerGas *big.Int) (types.TxData, error) {
+	if tx.Type() == types.DynamicFeeTxType {
+		feeCap, ok := bumpFee(tx.GasFeeCap(), bumpPercent, maxFeePerGas)
+		if !ok {
+			return nil, fmt.Errorf("%w, fee cap %s, max_fee_per_gas %s", ErrFeeCeiling, tx.GasFeeCap(), maxFeePerGas)
+		}
+		tipCap, ok := bumpFee(tx.GasTipCap(), bumpPercent, feeCap)
+		if !ok {
+			return nil, fmt.Errorf("%w, tip cap %s, fee cap %s", ErrFeeCeiling, tx.GasTipCap(), feeCap)
+		}
+		return &types.DynamicFeeTx{
+			ChainID:   tx.ChainId(),
+			Nonce:     tx.Nonce(),
+			GasTipCap: tipCap,
+			GasFeeCap: feeCap,
+			Gas:       tx.Gas(),
+			To:        tx.To(),
+			Value:     tx.Value(),
+			Data:      tx.Data(),
+		}, nil
+	}
+
+	gasPrice, ok := bumpFee(tx.GasPrice(), bumpPercent, maxGasPrice)
+	if !ok {
+		return nil, fmt.Errorf("%w, gas price %s, max_gas_price %s", ErrFeeCeiling, tx.GasPrice(), maxGasPrice)
+	}
+	return &types.LegacyTx{
+		Nonce:    tx.Nonce(),
+		GasPrice: gasPrice,
+		Gas:      tx.Gas(),
+		To:       tx.To(),
+		Value:    tx.Value(),
+		Data:     tx.Data(),
+	}, nil
+}
+
+/**
+raise fee by bumpPercent up to ceiling, false if that is below the minimum replacement fee
+*/
+func bumpFee(fee *big.Int, bumpPercent uint64, ceiling *big.Int) (*big.Int, bool) {
+	bumped := new(big.Int).Mul(fee, new(big.Int).SetUint64(100+bumpPercent))
+	bumped.Div(bumped, big.NewInt(100))
+	if ceiling.Sign() > 0 && bumped.Cmp(ceiling) > 0 {
+		bumped = new(big.Int).Set(ceiling)
+	}
+	return bumped, bumped.Cmp(minReplacementFee(fee)) >= 0
+}
+
+func minReplacementFee(fee *big.Int) *big.Int {
+	minFee := new(big.Int).Mul(fee, big.NewInt(100+config.MinGasPriceBumpPercent))
+	return minFee.Div(minFee, big.NewInt(100))
+}
+
+/**
+resend tx with the same nonce and a bumped fee
+*/
+func (executor *COREExecutor) replaceTx(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	coreCfg := &executor.cfg.COREConfig
+	txData, err := bumpTxFee(tx, coreCfg.GetGasPriceBumpPercent(),
+		new(big.Int).SetUint64(coreCfg.MaxGasPrice), new(big.Int).SetUint64(coreCfg.MaxFeePerGas))
+	if err != nil {
+		return nil, err
+	}
+
+	client := executor.GetClient()
+	chainId, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := executor.signer.SignTx(ctx, types.NewTx(txData), chainId)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.SendTransaction(ctx, signed); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
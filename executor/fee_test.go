@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+func TestComputeDynamicFee(t *testing.T) {
+	gwei := big.NewInt(1e9)
+
+	decision := computeDynamicFee(big.NewInt(30e9), big.NewInt(2e9), 1.5, big.NewInt(100e9))
+	require.Equal(t, config.FeeModeDynamic, decision.Mode)
+	require.Equal(t, big.NewInt(3e9), decision.GasTipCap)
+	require.Equal(t, big.NewInt(63e9), decision.GasFeeCap)
+	require.False(t, decision.Capped)
+
+	// fee cap and tip are both limited by max_fee_per_gas
+	decision = computeDynamicFee(big.NewInt(60e9), big.NewInt(50e9), 1, big.NewInt(40e9))
+	require.Equal(t, big.NewInt(40e9), decision.GasFeeCap)
+	require.Equal(t, big.NewInt(40e9), decision.GasTipCap)
+	require.True(t, decision.Capped)
+
+	decision = computeDynamicFee(big.NewInt(60e9), gwei, 1, big.NewInt(0))
+	require.Equal(t, big.NewInt(121e9), decision.GasFeeCap)
+}
+
+func TestFeeDecision_Apply(t *testing.T) {
+	txOpts := &bind.TransactOpts{GasPrice: big.NewInt(DefaultGasPrice)}
+
+	dynamic := computeDynamicFee(big.NewInt(30e9), big.NewInt(2e9), 1, big.NewInt(100e9))
+	dynamic.Apply(txOpts)
+	require.Nil(t, txOpts.GasPrice)
+	require.Equal(t, dynamic.GasTipCap, txOpts.GasTipCap)
+	require.Equal(t, dynamic.GasFeeCap, txOpts.GasFeeCap)
+
+	legacy := &FeeDecision{Mode: config.FeeModeLegacy, GasPrice: big.NewInt(DefaultGasPrice)}
+	legacy.Apply(txOpts)
+	require.Equal(t, big.NewInt(DefaultGasPrice), txOpts.GasPrice)
+	require.Nil(t, txOpts.GasTipCap)
+	require.Nil(t, txOpts.GasFeeCap)
+}
+
+func TestBumpTxFee(t *testing.T) {
+	gwei := big.NewInt(1e9)
+	legacy := types.NewTx(&types.LegacyTx{Nonce: 3, To: &pcsAddr, Value: big.NewInt(0), Gas: 300000, GasPrice: new(big.Int).Mul(big.NewInt(20), gwei), Data: []byte{0x01}})
+
+	txData, err := bumpTxFee(legacy, 25, new(big.Int).Mul(big.NewInt(100), gwei), big.NewInt(0))
+	require.NoError(t, err)
+	bumped := types.NewTx(txData)
+	require.Equal(t, legacy.Nonce(), bumped.Nonce())
+	require.Equal(t, legacy.Gas(), bumped.Gas())
+	require.Equal(t, legacy.Data(), bumped.Data())
+	require.Equal(t, new(big.Int).Mul(big.NewInt(25), gwei), bumped.GasPrice())
+
+	// capped, still a valid replacement
+	txData, err = bumpTxFee(legacy, 25, new(big.Int).Mul(big.NewInt(22), gwei), big.NewInt(0))
+	require.NoError(t, err)
+	require.Equal(t, new(big.Int).Mul(big.NewInt(22), gwei), types.NewTx(txData).GasPrice())
+
+	// capped below the minimum bump
+	_, err = bumpTxFee(legacy, 25, new(big.Int).Mul(big.NewInt(21), gwei), big.NewInt(0))
+	require.True(t, errors.Is(err, ErrFeeCeiling))
+
+	dynamic := types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(1116), Nonce: 3, To: &pcsAddr, Value: big.NewInt(0), Gas: 300000,
+		GasTipCap: new(big.Int).Mul(big.NewInt(2), gwei), GasFeeCap: new(big.Int).Mul(big.NewInt(40), gwei)})
+	txData, err = bumpTxFee(dynamic, 10, big.NewInt(0), new(big.Int).Mul(big.NewInt(100), gwei))
+	require.NoError(t, err)
+	bumped = types.NewTx(txData)
+	require.Equal(t, uint8(types.DynamicFeeTxType), bumped.Type())
+	require.Equal(t, big.NewInt(1116), bumped.ChainId())
+	require.Equal(t, new(big.Int).Mul(big.NewInt(44), gwei), bumped.GasFeeCap())
+	require.Equal(t, big.NewInt(2200000000), bumped.GasTipCap())
+
+	_, err = bumpTxFee(dynamic, 10, big.NewInt(0), new(big.Int).Mul(big.NewInt(42), gwei))
+	require.True(t, errors.Is(err, ErrFeeCeiling))
+}
@@ -0,0 +1,116 @@
+package executor
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/ethereum/go-ethereum"
+
+	relayercommon "github.com/coredao-org/btc-relayer/common"
+	cgccaller "github.com/coredao-org/btc-relayer/executor/cc"
+)
+
+/**
+simulate the relay tx before sending: the light client must accept the header in eth_call, then the gas is estimated.
+A header the light client rejects, by a revert or a nonzero return code, is a RelayError matching ErrSimulationReverted
+*/
+func (executor *COREExecutor) simulateRelay(ctx context.Context, data []byte, blockHash *chainhash.Hash) (uint64, error) {
+	_, rejected, err := executor.callStoreBlockHeader(ctx, data, blockHash)
+	if rejected != nil {
+		return 0, rejected
+	}
+	if err != nil {
+		relayercommon.Logger.Warningf("call storeBlockHeader error, blockHash:%s, err=%s", blockHash.String(), err.Error())
+	}
+	return executor.estimateRelayGas(ctx, data, blockHash)
+}
+
+/**
+call storeBlockHeader with eth_call from the relayer account, return its return code and the RelayError of a header
+the light client rejects. The returned error is a failure to call
+*/
+func (executor *COREExecutor) callStoreBlockHeader(ctx context.Context, data []byte, blockHash *chainhash.Hash) (int64, *RelayError, error) {
+	msg := ethereum.CallMsg{
+		From: executor.txSender,
+		To:   &pcsAddr,
+		Data: data,
+	}
+	output, err := executor.GetClient().CallContract(ctx, msg, nil)
+	if reason, reverted := revertReason(err); reverted {
+		return 0, &RelayError{
+			BlockHash: blockHash.String(),
+			Reason:    reason,
+			Simulated: true,
+			Err:       errorOfRevertReason(reason),
+		}, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	returnCode, err := cgccaller.UnpackStoreBlockHeader(output)
+	if err != nil {
+		return 0, nil, err
+	}
+	if returnCode == 0 {
+		return 0, nil, nil
+	}
+	return int64(returnCode), &RelayError{
+		BlockHash:  blockHash.String(),
+		ReturnCode: int64(returnCode),
+		Simulated:  true,
+		Err:        executor.errorOfReturnCode(int64(returnCode)),
+	}, nil
+}
+
+/**
+estimate the gas of storeBlockHeader with eth_estimateGas, add gas_margin_percent and cap at max_gas_limit.
+A revert in simulation returns a RelayError matching ErrSimulationReverted, other errors fall back to gas_limit
+*/
+func (executor *COREExecutor) estimateRelayGas(ctx context.Context, data []byte, blockHash *chainhash.Hash) (uint64, error) {
+	coreCfg := &executor.cfg.COREConfig
+	msg := ethereum.CallMsg{
+		From: executor.txSender,
+		To:   &pcsAddr,
+		Data: data,
+	}
+	estimated, err := executor.GetClient().EstimateGas(ctx, msg)
+	if err != nil {
+		if reason, reverted := revertReason(err); reverted {
+			return 0, &RelayError{
+				BlockHash: blockHash.String(),
+				Reason:    reason,
+				Simulated: true,
+				Err:       errorOfRevertReason(reason),
+			}
+		}
+		relayercommon.Logger.Warningf("estimate gas error, use gas_limit %d, err=%s", coreCfg.GasLimit, err.Error())
+		return coreCfg.GasLimit, nil
+	}
+
+	gasLimit := applyGasMargin(estimated, coreCfg.GetGasMarginPercent(), coreCfg.GetMaxGasLimit())
+	relayercommon.Logger.Infof("estimated gas:%d, gas limit:%d", estimated, gasLimit)
+	return gasLimit, nil
+}
+
+func applyGasMargin(estimated uint64, marginPercent uint64, maxGasLimit uint64) uint64 {
+	gasLimit := estimated * (100 + marginPercent) / 100
+	if gasLimit > maxGasLimit {
+		gasLimit = maxGasLimit
+	}
+	return gasLimit
+}
+
+/**
+raise the gas limit of a retry after out of gas, by gas_increase up to max_gas_limit
+*/
+func (executor *COREExecutor) increaseGas(gasLimit uint64) (uint64, bool) {
+	maxGasLimit := executor.cfg.COREConfig.GetMaxGasLimit()
+	if gasLimit >= maxGasLimit {
+		return gasLimit, false
+	}
+	gasLimit += executor.cfg.COREConfig.GasIncrease
+	if gasLimit > maxGasLimit {
+		gasLimit = maxGasLimit
+	}
+	return gasLimit, true
+}
@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+func TestApplyGasMargin(t *testing.T) {
+	require.Equal(t, uint64(120000), applyGasMargin(100000, 20, 4700000))
+	require.Equal(t, uint64(150000), applyGasMargin(100000, 50, 150000))
+	require.Equal(t, uint64(150000), applyGasMargin(200000, 20, 150000))
+}
+
+func TestEstimateRelayGas(t *testing.T) {
+	eth, server := newFakeCoreNode(t)
+	defer server.Close()
+
+	executor := newTestCOREExecutor(t, server.URL, config.COREConfig{GasLimit: 4700000, MaxGasLimit: 300000, GasIncrease: 100000})
+
+	eth.estimateGas = func(args map[string]interface{}) (hexutil.Uint64, error) {
+		require.Equal(t, pcsAddr, common.HexToAddress(args["to"].(string)))
+		require.Equal(t, executor.txSender, common.HexToAddress(args["from"].(string)))
+		return 200000, nil
+	}
+	gasLimit, err := executor.estimateRelayGas(context.Background(), []byte{0x01}, &chainhash.Hash{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(240000), gasLimit)
+
+	// capped at max_gas_limit
+	eth.estimateGas = func(args map[string]interface{}) (hexutil.Uint64, error) {
+		return 290000, nil
+	}
+	gasLimit, err = executor.estimateRelayGas(context.Background(), []byte{0x01}, &chainhash.Hash{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(300000), gasLimit)
+
+	// a revert is not retryable
+	eth.estimateGas = func(args map[string]interface{}) (hexutil.Uint64, error) {
+		return 0, &revertError{reason: "can not find previous block"}
+	}
+	_, err = executor.estimateRelayGas(context.Background(), []byte{0x01}, &chainhash.Hash{})
+	require.True(t, errors.Is(err, ErrSimulationReverted))
+	require.True(t, errors.Is(err, ErrNoPrevBlock))
+	require.Contains(t, err.Error(), "can not find previous block")
+
+	// other errors fall back to gas_limit
+	eth.estimateGas = func(args map[string]interface{}) (hexutil.Uint64, error) {
+		return 0, errors.New("internal error")
+	}
+	gasLimit, err = executor.estimateRelayGas(context.Background(), []byte{0x01}, &chainhash.Hash{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(4700000), gasLimit)
+}
+
+func TestSimulateRelay_ReturnCode(t *testing.T) {
+	eth, server := newFakeCoreNode(t)
+	defer server.Close()
+	executor := newTestCOREExecutor(t, server.URL, config.COREConfig{GasLimit: 4700000})
+	payload, err := BuildRelayPayload(newTestTask(t, 200))
+	require.NoError(t, err)
+
+	eth.call = storeBlockHeaderCall(t, 0, "")
+	gasLimit, err := executor.simulateRelay(context.Background(), payload.Data(), payload.Task.BlockHash)
+	require.NoError(t, err)
+	require.Equal(t, uint64(120000), gasLimit)
+
+	// the light client returns an error code without reverting, the gas estimate passes
+	eth.call = storeBlockHeaderCall(t, 10030, "")
+	_, err = executor.simulateRelay(context.Background(), payload.Data(), payload.Task.BlockHash)
+	var relayErr *RelayError
+	require.True(t, errors.As(err, &relayErr))
+	require.True(t, relayErr.Simulated)
+	require.Equal(t, int64(10030), relayErr.ReturnCode)
+	require.True(t, errors.Is(err, ErrNoPrevBlock))
+	require.True(t, errors.Is(err, ErrSimulationReverted))
+
+	// the header is not sent
+	_, err = executor.SubmitRelayPayload(context.Background(), payload, true)
+	require.True(t, errors.Is(err, ErrNoPrevBlock))
+	require.Empty(t, eth.sent)
+
+	// failing to call falls back to the gas estimate
+	eth.call = nil
+	gasLimit, err = executor.simulateRelay(context.Background(), payload.Data(), payload.Task.BlockHash)
+	require.NoError(t, err)
+	require.Equal(t, uint64(120000), gasLimit)
+}
+
+func TestIncreaseGas(t *testing.T) {
+	executor := &COREExecutor{cfg: &config.Config{COREConfig: config.COREConfig{GasLimit: 100000, MaxGasLimit: 250000, GasIncrease: 100000}}}
+
+	gasLimit, increased := executor.increaseGas(120000)
+	require.True(t, increased)
+	require.Equal(t, uint64(220000), gasLimit)
+
+	gasLimit, increased = executor.increaseGas(gasLimit)
+	require.True(t, increased)
+	require.Equal(t, uint64(250000), gasLimit)
+
+	_, increased = executor.increaseGas(gasLimit)
+	require.False(t, increased)
+	require.Equal(t, uint64(100000), executor.cfg.COREConfig.GasLimit)
+}
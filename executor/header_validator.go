@@ -0,0 +1,230 @@
+package executor
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// MedianTimeBlocks is the number of previous headers the median time past is taken over
+const MedianTimeBlocks = 11
+
+// HeaderCacheSize is the number of ancestor headers kept, enough for two retarget windows
+const HeaderCacheSize = 2 * 2016
+
+// HeaderValidator checks BTC headers against the consensus rules of the network before they are relayed:
+// proof of work, difficulty retargeting, median time past and the link to the previous header.
+// Ancestors are fetched from the btc source of the header and checked to link by hash
+type HeaderValidator struct {
+	params            *chaincfg.Params
+	blocksPerRetarget int64
+	minTimespan       int64
+	maxTimespan       int64
+	// regtest never retargets in bitcoind, the chaincfg params of this btcd version lack the flag
+	noRetargeting bool
+
+	mu      sync.Mutex
+	headers map[chainhash.Hash]*wire.BlockHeader
+	order   []chainhash.Hash
+}
+
+func NewHeaderValidator(params *chaincfg.Params) *HeaderValidator {
+	targetTimespan := int64(params.TargetTimespan / time.Second)
+	return &HeaderValidator{
+		params:            params,
+		blocksPerRetarget: int64(params.TargetTimespan / params.TargetTimePerBlock),
+		minTimespan:       targetTimespan / params.RetargetAdjustmentFactor,
+		maxTimespan:       targetTimespan * params.RetargetAdjustmentFactor,
+		noRetargeting:     params.Net == wire.TestNet,
+		headers:           make(map[chainhash.Hash]*wire.BlockHeader),
+	}
+}
+
+/**
+validate the header of the block at height, the errors wrap the light client error the contract would return
+*/
+func (validator *HeaderValidator) Validate(client BTCSource, height int64, header *wire.BlockHeader) error {
+	if err := validator.checkProofOfWork(header); err != nil {
+		return err
+	}
+	if height == 0 {
+		return nil
+	}
+
+	prevHeight, err := client.GetBlockHeight(&header.PrevBlock)
+	if err != nil {
+		return err
+	}
+	if prevHeight != height-1 {
+		return fmt.Errorf("%w: previous block %s at height %d", ErrPrevBlockMismatch, header.PrevBlock.String(), prevHeight)
+	}
+	prev, err := validator.ancestor(client, &header.PrevBlock)
+	if err != nil {
+		return err
+	}
+
+	if err := validator.checkMedianTimePast(client, header, prev); err != nil {
+		return err
+	}
+	if err := validator.checkDifficulty(client, height, header, prev); err != nil {
+		return err
+	}
+
+	validator.cache(header)
+	return nil
+}
+
+/**
+the target of bits must be within the proof of work limit, and the block hash must not exceed it
+*/
+func (validator *HeaderValidator) checkProofOfWork(header *wire.BlockHeader) error {
+	target := blockchain.CompactToBig(header.Bits)
+	if target.Sign() <= 0 || target.Cmp(validator.params.PowLimit) > 0 {
+		return fmt.Errorf("%w: target of bits %08x out of range", ErrProofOfWork, header.Bits)
+	}
+	blockHash := header.BlockHash()
+	if blockchain.HashToBig(&blockHash).Cmp(target) > 0 {
+		return fmt.Errorf("%w: block hash %s above target of bits %08x", ErrProofOfWork, blockHash.String(), header.Bits)
+	}
+	return nil
+}
+
+/**
+the timestamp must be after the median of the timestamps of the previous 11 headers
+*/
+func (validator *HeaderValidator) checkMedianTimePast(client BTCSource, header *wire.BlockHeader, prev *wire.BlockHeader) error {
+	timestamps := make([]int64, 0, MedianTimeBlocks)
+	node := prev
+	for len(timestamps) < MedianTimeBlocks {
+		timestamps = append(timestamps, node.Timestamp.Unix())
+		if node.PrevBlock == (chainhash.Hash{}) {
+			break
+		}
+		var err error
+		node, err = validator.ancestor(client, &node.PrevBlock)
+		if err != nil {
+			return err
+		}
+	}
+	//same as bitcoind, the upper middle one if the number is even
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	medianTime := timestamps[len(timestamps)/2]
+
+	if header.Timestamp.Unix() <= medianTime {
+		return fmt.Errorf("%w: timestamp %d, median time past %d", ErrTimestamp, header.Timestamp.Unix(), medianTime)
+	}
+	return nil
+}
+
+/**
+the bits must be the required difficulty after prev, see calcNextRequiredDifficulty of btcd
+*/
+func (validator *HeaderValidator) checkDifficulty(client BTCSource, height int64, header *wire.BlockHeader, prev *wire.BlockHeader) error {
+	expected, err := validator.requiredBits(client, height, header, prev)
+	if err != nil {
+		return err
+	}
+	if header.Bits == expected {
+		return nil
+	}
+	if height%validator.blocksPerRetarget == 0 {
+		return fmt.Errorf("%w: bits %08x, expected %08x", ErrRetarget, header.Bits, expected)
+	}
+	return fmt.Errorf("%w: bits %08x, expected %08x", ErrDifficulty, header.Bits, expected)
+}
+
+func (validator *HeaderValidator) requiredBits(client BTCSource, height int64, header *wire.BlockHeader, prev *wire.BlockHeader) (uint32, error) {
+	params := validator.params
+	if validator.noRetargeting {
+		return prev.Bits, nil
+	}
+
+	if height%validator.blocksPerRetarget != 0 {
+		if !params.ReduceMinDifficulty {
+			return prev.Bits, nil
+		}
+		//testnet: the minimum difficulty is allowed after no block for twice the target time per block
+		if header.Timestamp.After(prev.Timestamp.Add(params.MinDiffReductionTime)) {
+			return params.PowLimitBits, nil
+		}
+		//else the difficulty of the last block without the special rule
+		node, nodeHeight := prev, height-1
+		for nodeHeight%validator.blocksPerRetarget != 0 && node.Bits == params.PowLimitBits {
+			var err error
+			node, err = validator.ancestor(client, &node.PrevBlock)
+			if err != nil {
+				return 0, err
+			}
+			nodeHeight--
+		}
+		return node.Bits, nil
+	}
+
+	//the first block of the retarget window ending at prev
+	first := prev
+	for i := int64(1); i < validator.blocksPerRetarget; i++ {
+		var err error
+		first, err = validator.ancestor(client, &first.PrevBlock)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	timespan := prev.Timestamp.Unix() - first.Timestamp.Unix()
+	if timespan < validator.minTimespan {
+		timespan = validator.minTimespan
+	} else if timespan > validator.maxTimespan {
+		timespan = validator.maxTimespan
+	}
+
+	target := new(big.Int).Mul(blockchain.CompactToBig(prev.Bits), big.NewInt(timespan))
+	target.Div(target, big.NewInt(int64(params.TargetTimespan/time.Second)))
+	if target.Cmp(params.PowLimit) > 0 {
+		target.Set(params.PowLimit)
+	}
+	return blockchain.BigToCompact(target), nil
+}
+
+/**
+get the header of hash, from the cache or the btc source. A header not hashing to hash breaks the linkage
+*/
+func (validator *HeaderValidator) ancestor(client BTCSource, hash *chainhash.Hash) (*wire.BlockHeader, error) {
+	validator.mu.Lock()
+	header, ok := validator.headers[*hash]
+	validator.mu.Unlock()
+	if ok {
+		return header, nil
+	}
+
+	header, err := client.GetBlockHeader(hash)
+	if err != nil {
+		return nil, err
+	}
+	if header.BlockHash() != *hash {
+		return nil, fmt.Errorf("%w: header of block %s has hash %s", ErrPrevBlockMismatch, hash.String(), header.BlockHash().String())
+	}
+	validator.cache(header)
+	return header, nil
+}
+
+func (validator *HeaderValidator) cache(header *wire.BlockHeader) {
+	validator.mu.Lock()
+	defer validator.mu.Unlock()
+	blockHash := header.BlockHash()
+	if _, ok := validator.headers[blockHash]; ok {
+		return
+	}
+	validator.headers[blockHash] = header
+	validator.order = append(validator.order, blockHash)
+	if len(validator.order) > HeaderCacheSize {
+		delete(validator.headers, validator.order[0])
+		validator.order = validator.order[1:]
+	}
+}
@@ -0,0 +1,208 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coredao-org/btc-relayer/common"
+)
+
+// headerChain is a BTCSource serving headers only, headers[0] is the genesis
+type headerChain struct {
+	headers []*wire.BlockHeader
+	// forged headers are served for a hash instead of the real ones
+	forged map[chainhash.Hash]*wire.BlockHeader
+}
+
+func newHeaderChain(genesis wire.BlockHeader) *headerChain {
+	return &headerChain{headers: []*wire.BlockHeader{&genesis}, forged: make(map[chainhash.Hash]*wire.BlockHeader)}
+}
+
+/**
+mine a header on the tip, delta after it
+*/
+func (chain *headerChain) mine(bits uint32, delta time.Duration) *wire.BlockHeader {
+	tip := chain.headers[len(chain.headers)-1]
+	tipHash := tip.BlockHash()
+	header := wire.NewBlockHeader(0x20000000, &tipHash, &chainhash.Hash{byte(len(chain.headers))}, bits, 0)
+	header.Timestamp = tip.Timestamp.Add(delta)
+	solve(header)
+	return header
+}
+
+func (chain *headerChain) add(header *wire.BlockHeader) {
+	chain.headers = append(chain.headers, header)
+}
+
+func solve(header *wire.BlockHeader) {
+	target := blockchain.CompactToBig(header.Bits)
+	for {
+		blockHash := header.BlockHash()
+		if blockchain.HashToBig(&blockHash).Cmp(target) <= 0 {
+			return
+		}
+		header.Nonce++
+	}
+}
+
+func (chain *headerChain) GetBlockCount() (int64, error) {
+	return int64(len(chain.headers) - 1), nil
+}
+
+func (chain *headerChain) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	if height < 0 || height >= int64(len(chain.headers)) {
+		return nil, fmt.Errorf("no block at height %d", height)
+	}
+	blockHash := chain.headers[height].BlockHash()
+	return &blockHash, nil
+}
+
+func (chain *headerChain) GetBlockHeader(hash *chainhash.Hash) (*wire.BlockHeader, error) {
+	if header, ok := chain.forged[*hash]; ok {
+		return header, nil
+	}
+	height, err := chain.GetBlockHeight(hash)
+	if err != nil {
+		return nil, err
+	}
+	return chain.headers[height], nil
+}
+
+func (chain *headerChain) GetBlockHeight(hash *chainhash.Hash) (int64, error) {
+	for height, header := range chain.headers {
+		if header.BlockHash() == *hash {
+			return int64(height), nil
+		}
+	}
+	return 0, fmt.Errorf("no block %s", hash.String())
+}
+
+func (chain *headerChain) GetBlockTxHashes(hash *chainhash.Hash) ([]chainhash.Hash, error) {
+	return nil, errors.New("headers only")
+}
+
+func (chain *headerChain) GetRawTransaction(txHash *chainhash.Hash, blockHash *chainhash.Hash) (*wire.MsgTx, error) {
+	return nil, errors.New("headers only")
+}
+
+func (chain *headerChain) GetBlock(hash *chainhash.Hash) (*wire.MsgBlock, error) {
+	return nil, errors.New("headers only")
+}
+
+func TestHeaderValidator_Regtest(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	chain := newHeaderChain(params.GenesisBlock.Header)
+	validator := NewHeaderValidator(params)
+	for height := int64(1); height <= 20; height++ {
+		header := chain.mine(params.PowLimitBits, time.Minute)
+		require.NoError(t, validator.Validate(chain, height, header))
+		chain.add(header)
+	}
+	height := int64(len(chain.headers))
+
+	t.Run("proof of work", func(t *testing.T) {
+		header := chain.mine(params.PowLimitBits, time.Minute)
+		for blockHash := header.BlockHash(); blockchain.HashToBig(&blockHash).Cmp(params.PowLimit) <= 0; blockHash = header.BlockHash() {
+			header.Nonce++
+		}
+		require.ErrorIs(t, validator.Validate(chain, height, header), ErrProofOfWork)
+
+		//bits above the proof of work limit
+		header = chain.mine(0x2100ffff, time.Minute)
+		require.ErrorIs(t, validator.Validate(chain, height, header), ErrProofOfWork)
+	})
+
+	t.Run("difficulty", func(t *testing.T) {
+		header := chain.mine(0x201fffff, time.Minute)
+		require.ErrorIs(t, validator.Validate(chain, height, header), ErrDifficulty)
+	})
+
+	t.Run("median time past", func(t *testing.T) {
+		//the median of the last 11 timestamps is 5 minutes before the tip
+		header := chain.mine(params.PowLimitBits, -5*time.Minute)
+		require.ErrorIs(t, validator.Validate(chain, height, header), ErrTimestamp)
+		header = chain.mine(params.PowLimitBits, -4*time.Minute)
+		require.NoError(t, validator.Validate(chain, height, header))
+	})
+
+	t.Run("linkage", func(t *testing.T) {
+		header := chain.mine(params.PowLimitBits, time.Minute)
+		require.ErrorIs(t, validator.Validate(chain, height+1, header), ErrPrevBlockMismatch)
+
+		//the source serves another header for an ancestor out of the cache
+		forgedChain := newHeaderChain(params.GenesisBlock.Header)
+		forgedChain.headers = chain.headers
+		forged := *chain.headers[height-5]
+		forged.Timestamp = forged.Timestamp.Add(time.Hour)
+		forgedChain.forged[chain.headers[height-5].BlockHash()] = &forged
+		require.ErrorIs(t, NewHeaderValidator(params).Validate(forgedChain, height, header), ErrPrevBlockMismatch)
+	})
+}
+
+func TestHeaderValidator_Retarget(t *testing.T) {
+	//retarget every 4 blocks, on the regtest proof of work limit
+	params := chaincfg.RegressionNetParams
+	params.Net = wire.MainNet
+	params.ReduceMinDifficulty = false
+	params.TargetTimespan = 4 * params.TargetTimePerBlock
+	chain := newHeaderChain(params.GenesisBlock.Header)
+	validator := NewHeaderValidator(&params)
+
+	for height := int64(1); height < 4; height++ {
+		header := chain.mine(params.PowLimitBits, time.Second)
+		require.NoError(t, validator.Validate(chain, height, header))
+		chain.add(header)
+	}
+
+	//blocks came fast, the target drops by the adjustment factor
+	target := new(big.Int).Div(params.PowLimit, big.NewInt(params.RetargetAdjustmentFactor))
+	header := chain.mine(params.PowLimitBits, time.Second)
+	require.ErrorIs(t, validator.Validate(chain, 4, header), ErrRetarget)
+	header = chain.mine(blockchain.BigToCompact(target), time.Second)
+	require.NoError(t, validator.Validate(chain, 4, header))
+	chain.add(header)
+
+	//no change within the window
+	header = chain.mine(params.PowLimitBits, time.Hour)
+	require.ErrorIs(t, validator.Validate(chain, 5, header), ErrDifficulty)
+	header = chain.mine(blockchain.BigToCompact(target), time.Hour)
+	require.NoError(t, validator.Validate(chain, 5, header))
+}
+
+func TestBTCExecutor_ValidateHeader(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	chain := newHeaderChain(params.GenesisBlock.Header)
+	chain.add(chain.mine(params.PowLimitBits, time.Minute))
+	executor := &BTCExecutor{
+		BTCClients: []*BTCClient{{BTCClient: chain, Provider: "127.0.0.1:18443"}},
+		validator:  NewHeaderValidator(params),
+	}
+
+	header := chain.mine(params.PowLimitBits, time.Minute)
+	blockHash := header.BlockHash()
+	task := &common.Task{Height: 2, BlockHash: &blockHash, PARTS: &common.BlockParts{Header: *header}}
+	require.NoError(t, executor.ValidateHeader(chain, task))
+
+	//the header is not the one of the block hash
+	task.PARTS.Header.Nonce++
+	err := executor.ValidateHeader(chain, task)
+	var headerErr *HeaderError
+	require.ErrorAs(t, err, &headerErr)
+	require.Equal(t, int64(2), headerErr.Height)
+	require.Equal(t, "127.0.0.1:18443", headerErr.Provider)
+
+	task.PARTS.Header = *chain.mine(params.PowLimitBits, -time.Hour)
+	blockHash = task.PARTS.Header.BlockHash()
+	err = executor.ValidateHeader(chain, task)
+	require.ErrorAs(t, err, &headerErr)
+	require.ErrorIs(t, err, ErrTimestamp)
+}
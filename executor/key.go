@@ -0,0 +1,125 @@
+package executor
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+/**
+load the relayer key according to key_type, from the config file or from a secret provider
+*/
+func getPrivateKey(cfg *config.COREConfig) (*ecdsa.PrivateKey, error) {
+	source, kind, err := config.ParseKeyType(cfg.KeyType)
+	if err != nil {
+		return nil, err
+	}
+
+	var secret string
+	if source == config.LocalConfig {
+		switch kind {
+		case config.KeyKindMnemonic:
+			secret = cfg.Mnemonic
+		case config.KeyKindKeystore:
+			keyJson, err := ioutil.ReadFile(cfg.KeystorePath)
+			if err != nil {
+				return nil, err
+			}
+			secret = string(keyJson)
+		default:
+			secret = cfg.PrivateKey
+		}
+	} else {
+		provider, err := config.NewSecretProvider(source, cfg)
+		if err != nil {
+			return nil, err
+		}
+		secret, err = provider.GetSecret(cfg.SecretName)
+		if err != nil {
+			return nil, fmt.Errorf("get secret %s from %s error: %s", cfg.SecretName, source, err.Error())
+		}
+	}
+	secret = strings.TrimSpace(secret)
+
+	switch kind {
+	case config.KeyKindMnemonic:
+		return mnemonicToPrivateKey(secret, cfg.GetDerivationPath())
+	case config.KeyKindKeystore:
+		password, err := GetKeystorePassword(cfg.KeystorePasswordFile, cfg.GetKeystorePasswordEnv())
+		if err != nil {
+			return nil, err
+		}
+		key, err := keystore.DecryptKey([]byte(secret), password)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt keystore error: %s", err.Error())
+		}
+		return key.PrivateKey, nil
+	}
+
+	privKey, err := crypto.HexToECDSA(strings.TrimPrefix(secret, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	return privKey, nil
+}
+
+/**
+derive the key at derivationPath from a BIP-39 mnemonic, with an empty passphrase
+*/
+func mnemonicToPrivateKey(mnemonic string, derivationPath string) (*ecdsa.PrivateKey, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := accounts.ParseDerivationPath(derivationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range path {
+		key, err = key.Derive(idx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	privKey, err := key.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	return privKey.ToECDSA(), nil
+}
+
+/**
+read the keystore password from passwordFile, or from the env passwordEnv if no file is given
+*/
+func GetKeystorePassword(passwordFile string, passwordEnv string) (string, error) {
+	if passwordFile != "" {
+		bz, err := ioutil.ReadFile(passwordFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(bz), "\r\n"), nil
+	}
+	password, ok := os.LookupEnv(passwordEnv)
+	if !ok {
+		return "", fmt.Errorf("keystore password not found, set a password file or env %s", passwordEnv)
+	}
+	return password, nil
+}
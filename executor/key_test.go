@@ -0,0 +1,143 @@
+package executor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+const (
+	testMnemonic   = "test test test test test test test test test test test junk"
+	testPrivateKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+	testAddress    = "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+)
+
+// newSecretsManagerStandIn serves GetSecretValue of the AWS Secrets Manager json protocol
+func newSecretsManagerStandIn(t *testing.T, secrets map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+
+		var input struct {
+			SecretId string
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&input))
+
+		secret, ok := secrets[input.SecretId]
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException","Message":"Secrets Manager can't find the specified secret."}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"Name": input.SecretId, "SecretString": secret})
+	}))
+}
+
+func TestGetPrivateKey_Local(t *testing.T) {
+	privKey, err := getPrivateKey(&config.COREConfig{PrivateKey: "0x" + testPrivateKey})
+	require.NoError(t, err)
+	require.Equal(t, testAddress, crypto.PubkeyToAddress(privKey.PublicKey).String())
+
+	privKey, err = getPrivateKey(&config.COREConfig{KeyType: config.KeyTypeMnemonic, Mnemonic: testMnemonic})
+	require.NoError(t, err)
+	require.Equal(t, testAddress, crypto.PubkeyToAddress(privKey.PublicKey).String())
+
+	privKey, err = getPrivateKey(&config.COREConfig{KeyType: config.KeyTypeMnemonic, Mnemonic: testMnemonic, DerivationPath: "m/44'/60'/0'/0/1"})
+	require.NoError(t, err)
+	require.Equal(t, "0x70997970C51812dc3A010C7d01b50e0d17dc79C8", crypto.PubkeyToAddress(privKey.PublicKey).String())
+
+	_, err = getPrivateKey(&config.COREConfig{KeyType: config.KeyTypeMnemonic, Mnemonic: "test test test"})
+	require.Error(t, err)
+}
+
+func TestGetPrivateKey_AWS(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	server := newSecretsManagerStandIn(t, map[string]string{
+		"relayer/private_key": testPrivateKey + "\n",
+		"relayer/mnemonic":    testMnemonic,
+	})
+	defer server.Close()
+
+	coreCfg := &config.COREConfig{
+		KeyType:     config.KeyTypeAWSPrivateKey,
+		SecretName:  "relayer/private_key",
+		AWSRegion:   "us-east-1",
+		AWSEndpoint: server.URL,
+	}
+	privKey, err := getPrivateKey(coreCfg)
+	require.NoError(t, err)
+	require.Equal(t, testAddress, crypto.PubkeyToAddress(privKey.PublicKey).String())
+
+	coreCfg.KeyType = config.KeyTypeAWSMnemonic
+	coreCfg.SecretName = "relayer/mnemonic"
+	privKey, err = getPrivateKey(coreCfg)
+	require.NoError(t, err)
+	require.Equal(t, testAddress, crypto.PubkeyToAddress(privKey.PublicKey).String())
+
+	coreCfg.SecretName = "relayer/missing"
+	_, err = getPrivateKey(coreCfg)
+	require.Error(t, err)
+}
+
+type staticSecretProvider map[string]string
+
+func (provider staticSecretProvider) GetSecret(name string) (string, error) {
+	return provider[name], nil
+}
+
+func TestGetPrivateKey_RegisteredProvider(t *testing.T) {
+	config.RegisterSecretProvider("vault", func(cfg *config.COREConfig) (config.SecretProvider, error) {
+		return staticSecretProvider{"relayer": testPrivateKey}, nil
+	})
+
+	privKey, err := getPrivateKey(&config.COREConfig{KeyType: "vault_private_key", SecretName: "relayer"})
+	require.NoError(t, err)
+	require.Equal(t, testAddress, crypto.PubkeyToAddress(privKey.PublicKey).String())
+
+	_, err = getPrivateKey(&config.COREConfig{KeyType: "consul_private_key"})
+	require.Error(t, err)
+}
+
+func TestGetPrivateKey_Keystore(t *testing.T) {
+	dir := t.TempDir()
+	privKey, err := crypto.HexToECDSA(testPrivateKey)
+	require.NoError(t, err)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.ImportECDSA(privKey, "relayer-password")
+	require.NoError(t, err)
+
+	passwordFile := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("relayer-password\n"), 0600))
+
+	coreCfg := &config.COREConfig{
+		KeyType:              config.KeyTypeLocalKeystore,
+		KeystorePath:         account.URL.Path,
+		KeystorePasswordFile: passwordFile,
+	}
+	loaded, err := getPrivateKey(coreCfg)
+	require.NoError(t, err)
+	require.Equal(t, testAddress, crypto.PubkeyToAddress(loaded.PublicKey).String())
+
+	coreCfg.KeystorePasswordFile = ""
+	coreCfg.KeystorePasswordEnv = "TEST_RELAYER_KEYSTORE_PASSWORD"
+	t.Setenv("TEST_RELAYER_KEYSTORE_PASSWORD", "wrong")
+	_, err = getPrivateKey(coreCfg)
+	require.Error(t, err)
+
+	t.Setenv("TEST_RELAYER_KEYSTORE_PASSWORD", "relayer-password")
+	loaded, err = getPrivateKey(coreCfg)
+	require.NoError(t, err)
+	require.Equal(t, testAddress, crypto.PubkeyToAddress(loaded.PublicKey).String())
+}
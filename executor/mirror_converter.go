@@ -5,6 +5,8 @@ import (
 	"github.com/btcsuite/btcd/wire"
 	"github.com/coredao-org/btcpowermirror/lightmirror"
 	"github.com/jinzhu/copier"
+
+	"github.com/coredao-org/btc-relayer/common"
 )
 
 func NewBtcLightMirror(block *wire.MsgBlock) *lightmirror.BtcLightMirrorV2 {
@@ -14,6 +16,20 @@ func NewBtcLightMirror(block *wire.MsgBlock) *lightmirror.BtcLightMirrorV2 {
 	)
 }
 
+func NewBtcLightMirrorFromParts(parts *common.BlockParts) *lightmirror.BtcLightMirrorV2 {
+	return lightmirror.CreateBtcLightMirrorV2(&parts.Header,
+		parts.Coinbase,
+		parts.TxHashes,
+	)
+}
+
+func newTaskLightMirror(task *common.Task) *lightmirror.BtcLightMirrorV2 {
+	if task.PARTS != nil {
+		return NewBtcLightMirrorFromParts(task.PARTS)
+	}
+	return NewBtcLightMirror(task.BLOCK)
+}
+
 func fillTxHashes(transactions []*wire.MsgTx) []chainhash.Hash {
 	txHashes := make([]chainhash.Hash, len(transactions))
 
@@ -0,0 +1,170 @@
+package executor
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	relayercommon "github.com/coredao-org/btc-relayer/common"
+)
+
+// nonceSource returns the pending nonce of the relayer account on chain
+type nonceSource func(ctx context.Context) (uint64, error)
+
+// NonceManager hands out the nonces of the relayer account locally, so txs can be sent back-to-back
+// without asking a provider for the pending nonce each time
+type NonceManager struct {
+	mu     sync.Mutex
+	source nonceSource
+	synced bool
+	// next nonce never handed out
+	next uint64
+	// nonces handed out whose tx is not known to be included yet
+	inFlight map[uint64]bool
+	// nonces handed out but not used by a tx, sorted, handed out again first
+	gaps []uint64
+}
+
+func newNonceManager(source nonceSource) *NonceManager {
+	return &NonceManager{
+		source:   source,
+		inFlight: make(map[uint64]bool),
+	}
+}
+
+/**
+reserve the lowest free nonce, syncing from chain the first time
+*/
+func (manager *NonceManager) Next(ctx context.Context) (uint64, error) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if !manager.synced {
+		if err := manager.resync(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	var nonce uint64
+	if len(manager.gaps) > 0 {
+		nonce = manager.gaps[0]
+		manager.gaps = manager.gaps[1:]
+	} else {
+		nonce = manager.next
+		manager.next++
+	}
+	manager.inFlight[nonce] = true
+	return nonce, nil
+}
+
+/**
+the tx of nonce is included, whether it succeeded or not
+*/
+func (manager *NonceManager) Confirm(nonce uint64) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	delete(manager.inFlight, nonce)
+}
+
+/**
+the tx of nonce was not sent or was dropped, the nonce is handed out again
+*/
+func (manager *NonceManager) Release(nonce uint64) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	if !manager.inFlight[nonce] {
+		return
+	}
+	delete(manager.inFlight, nonce)
+	manager.addGap(nonce)
+}
+
+/**
+sync with the pending nonce on chain, after a nonce error or a dropped tx
+*/
+func (manager *NonceManager) Resync(ctx context.Context) error {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	return manager.resync(ctx)
+}
+
+/**
+Send reserves a nonce and calls send with it. The nonce is released if send fails,
+on a nonce error the manager resyncs and send is called once more with a new nonce
+*/
+func (manager *NonceManager) Send(ctx context.Context, send func(nonce uint64) error) (uint64, error) {
+	for attempt := 0; ; attempt++ {
+		nonce, err := manager.Next(ctx)
+		if err != nil {
+			return 0, err
+		}
+		err = send(nonce)
+		if err == nil {
+			return nonce, nil
+		}
+		if !isNonceError(err) || attempt > 0 {
+			manager.Release(nonce)
+			return 0, err
+		}
+
+		relayercommon.Logger.Warningf("send tx with nonce %d error, resync nonce, err=%s", nonce, err.Error())
+		if resyncErr := manager.Resync(ctx); resyncErr != nil {
+			return 0, resyncErr
+		}
+	}
+}
+
+func (manager *NonceManager) resync(ctx context.Context) error {
+	pending, err := manager.source(ctx)
+	if err != nil {
+		return err
+	}
+
+	//nonces below pending are used on chain
+	for nonce := range manager.inFlight {
+		if nonce < pending {
+			delete(manager.inFlight, nonce)
+		}
+	}
+	gaps := manager.gaps[:0]
+	for _, nonce := range manager.gaps {
+		if nonce >= pending {
+			gaps = append(gaps, nonce)
+		}
+	}
+	manager.gaps = gaps
+
+	if !manager.synced || pending > manager.next {
+		manager.next = pending
+	} else {
+		//txs from pending on were lost by the provider, fill the gaps
+		for nonce := pending; nonce < manager.next; nonce++ {
+			if !manager.inFlight[nonce] {
+				manager.addGap(nonce)
+			}
+		}
+	}
+	manager.synced = true
+	relayercommon.Logger.Infof("nonce synced, pending:%d, next:%d, in flight:%d, gaps:%v", pending, manager.next, len(manager.inFlight), manager.gaps)
+	return nil
+}
+
+func (manager *NonceManager) addGap(nonce uint64) {
+	idx := sort.Search(len(manager.gaps), func(i int) bool { return manager.gaps[i] >= nonce })
+	if idx < len(manager.gaps) && manager.gaps[idx] == nonce {
+		return
+	}
+	manager.gaps = append(manager.gaps, 0)
+	copy(manager.gaps[idx+1:], manager.gaps[idx:])
+	manager.gaps[idx] = nonce
+}
+
+/**
+errors of a node that mean the local nonce is out of sync with the chain
+*/
+func isNonceError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "nonce too low") ||
+		strings.Contains(msg, "replacement transaction underpriced")
+}
@@ -0,0 +1,183 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+
+	relayercommon "github.com/coredao-org/btc-relayer/common"
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+// chainNonce is the pending nonce on chain, counting how often it is queried
+type chainNonce struct {
+	mu      sync.Mutex
+	pending uint64
+	queries int
+}
+
+func (chain *chainNonce) source(ctx context.Context) (uint64, error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+	chain.queries++
+	return chain.pending, nil
+}
+
+func TestNonceManager_Next(t *testing.T) {
+	chain := &chainNonce{pending: 5}
+	manager := newNonceManager(chain.source)
+
+	for expected := uint64(5); expected < 10; expected++ {
+		nonce, err := manager.Next(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, expected, nonce)
+	}
+	// synced once, then handed out locally
+	require.Equal(t, 1, chain.queries)
+
+	// a nonce not used is handed out again first
+	manager.Release(7)
+	nonce, err := manager.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), nonce)
+	nonce, err = manager.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), nonce)
+}
+
+func TestNonceManager_Concurrent(t *testing.T) {
+	manager := newNonceManager((&chainNonce{}).source)
+
+	var wg sync.WaitGroup
+	nonces := make(chan uint64, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := manager.Next(context.Background())
+			require.NoError(t, err)
+			nonces <- nonce
+		}()
+	}
+	wg.Wait()
+	close(nonces)
+
+	seen := make(map[uint64]bool)
+	for nonce := range nonces {
+		require.False(t, seen[nonce])
+		seen[nonce] = true
+	}
+	require.Len(t, seen, 100)
+}
+
+func TestNonceManager_ResyncFillsGaps(t *testing.T) {
+	chain := &chainNonce{pending: 0}
+	manager := newNonceManager(chain.source)
+	for i := 0; i < 4; i++ {
+		_, err := manager.Next(context.Background())
+		require.NoError(t, err)
+	}
+	manager.Confirm(0)
+	manager.Confirm(1)
+
+	// 0, 1 are included, 2 is pending, 3 was included and then reorged out
+	manager.Confirm(3)
+	chain.pending = 2
+	require.NoError(t, manager.Resync(context.Background()))
+
+	nonce, err := manager.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), nonce)
+	nonce, err = manager.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), nonce)
+
+	// another sender used the account
+	chain.pending = 9
+	require.NoError(t, manager.Resync(context.Background()))
+	nonce, err = manager.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(9), nonce)
+}
+
+func TestNonceManager_Send(t *testing.T) {
+	chain := &chainNonce{pending: 3}
+	manager := newNonceManager(chain.source)
+
+	// the chain moved on, resync and retry once
+	var tried []uint64
+	nonce, err := manager.Send(context.Background(), func(nonce uint64) error {
+		tried = append(tried, nonce)
+		if nonce < 6 {
+			chain.pending = 6
+			return errors.New("nonce too low")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), nonce)
+	require.Equal(t, []uint64{3, 6}, tried)
+
+	// the nonce of a failed send is released
+	_, err = manager.Send(context.Background(), func(nonce uint64) error {
+		return errors.New("insufficient funds for gas * price + value")
+	})
+	require.Error(t, err)
+	nonce, err = manager.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), nonce)
+}
+
+func newTestTask(t *testing.T, height int64) *relayercommon.Task {
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, 0xffffffff), []byte{byte(height)}, nil))
+	coinbase.AddTxOut(wire.NewTxOut(625000000, []byte{0x51}))
+	block := wire.NewMsgBlock(wire.NewBlockHeader(1, &chainhash.Hash{}, &chainhash.Hash{}, 0x1d00ffff, uint32(height)))
+	require.NoError(t, block.AddTransaction(coinbase))
+	blockHash := block.BlockHash()
+	return &relayercommon.Task{Height: height, BlockHash: &blockHash, BLOCK: block}
+}
+
+func TestSubmitBTCLightMirror_BackToBack(t *testing.T) {
+	eth, server := newFakeCoreNode(t)
+	defer server.Close()
+	eth.nonce = 11
+	executor := newTestCOREExecutor(t, server.URL, config.COREConfig{GasLimit: 4700000})
+
+	for i := int64(0); i < 3; i++ {
+		submission, err := executor.SubmitBTCLightMirror(context.Background(), newTestTask(t, 100+i))
+		require.NoError(t, err)
+		require.Equal(t, uint64(11+i), submission.Tx.Nonce())
+	}
+	require.Len(t, eth.sent, 3)
+}
+
+func TestCOREExecutor_SettleSupersededNonce(t *testing.T) {
+	eth, server := newFakeCoreNode(t)
+	defer server.Close()
+	eth.nonce = 5
+	executor := newTestCOREExecutor(t, server.URL, config.COREConfig{GasLimit: 4700000})
+	for i := 0; i < 2; i++ {
+		_, err := executor.nonces.Next(context.Background())
+		require.NoError(t, err)
+	}
+
+	// a competitor stored the header, our tx of nonce 5 is not known to the chain, the nonce is handed out again
+	executor.settleNonce(5, &RelayResult{Outcome: TxSuperseded})
+	require.False(t, executor.nonces.inFlight[5])
+	nonce, err := executor.nonces.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), nonce)
+
+	// the tx of nonce 6 holds its nonce on chain
+	eth.nonce = 7
+	executor.settleNonce(6, &RelayResult{Outcome: TxSuperseded})
+	require.False(t, executor.nonces.inFlight[6])
+	require.Empty(t, executor.nonces.gaps)
+	require.True(t, executor.nonces.inFlight[5])
+}
@@ -0,0 +1,568 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/peer"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/coredao-org/btc-relayer/common"
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+const (
+	P2PUserAgentName    = "btc-relayer"
+	P2PUserAgentVersion = "1.0.0"
+
+	// P2PRequestTimeout is the time to wait for a block requested from a peer
+	P2PRequestTimeout    = 30 * time.Second
+	P2PReconnectInterval = 5 * time.Second
+	// P2PBlockCacheSize is the number of fetched blocks kept, the parts of a block are read from one fetch
+	P2PBlockCacheSize = 8
+)
+
+func netParams(network string) (*chaincfg.Params, error) {
+	switch network {
+	case "", config.BTCNetworkMainnet:
+		return &chaincfg.MainNetParams, nil
+	case config.BTCNetworkTestnet3:
+		return &chaincfg.TestNet3Params, nil
+	case config.BTCNetworkSignet:
+		return &chaincfg.SigNetParams, nil
+	case config.BTCNetworkRegtest:
+		return &chaincfg.RegressionNetParams, nil
+	}
+	return nil, fmt.Errorf("unknown btc network %q", network)
+}
+
+// p2pSource is a Bitcoin peer. Headers are synced with getheaders from the last checkpoint of the network,
+// full blocks are fetched from the peer only when needed
+type p2pSource struct {
+	addr           string
+	params         *chaincfg.Params
+	requestTimeout time.Duration
+	validator      *HeaderValidator
+	// ctx is cancelled by stop, done is closed once the connection loop has returned
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu   sync.RWMutex
+	peer *peer.Peer
+	// hashes is the best header chain, hashes[i] is the block at height baseHeight+i
+	baseHeight int64
+	hashes     []chainhash.Hash
+	heights    map[chainhash.Hash]int64
+	// the header of the base block is unknown if it is a checkpoint
+	headers map[chainhash.Hash]*wire.BlockHeader
+	// synced is set once the peer has no more headers to send
+	synced bool
+
+	blockMu sync.Mutex
+	blocks  map[chainhash.Hash]*wire.MsgBlock
+	// blockOrder is the order the cached blocks were fetched in
+	blockOrder []chainhash.Hash
+	waiting    map[chainhash.Hash][]chan *wire.MsgBlock
+}
+
+/**
+create the source and connect to the peer, the connection is kept until stop
+*/
+func newP2PSource(addr string, params *chaincfg.Params) *p2pSource {
+	ctx, cancel := context.WithCancel(context.Background())
+	source := &p2pSource{
+		addr:           addr,
+		params:         params,
+		requestTimeout: P2PRequestTimeout,
+		validator:      NewHeaderValidator(params),
+		ctx:            ctx,
+		cancel:         cancel,
+		done:           make(chan struct{}),
+		heights:        make(map[chainhash.Hash]int64),
+		headers:        make(map[chainhash.Hash]*wire.BlockHeader),
+		blocks:         make(map[chainhash.Hash]*wire.MsgBlock),
+		waiting:        make(map[chainhash.Hash][]chan *wire.MsgBlock),
+	}
+
+	if len(params.Checkpoints) > 0 {
+		checkpoint := params.Checkpoints[len(params.Checkpoints)-1]
+		source.baseHeight = int64(checkpoint.Height)
+		source.hashes = []chainhash.Hash{*checkpoint.Hash}
+	} else {
+		genesis := params.GenesisBlock.Header
+		source.hashes = []chainhash.Hash{genesis.BlockHash()}
+		source.headers[genesis.BlockHash()] = &genesis
+	}
+	source.heights[source.hashes[0]] = source.baseHeight
+
+	go source.run()
+	return source
+}
+
+/**
+disconnect from the peer and wait for the connection loop to return
+*/
+func (source *p2pSource) stop() {
+	source.cancel()
+	<-source.done
+}
+
+/**
+keep a connection to the peer, connecting again after a disconnect, until stop
+*/
+func (source *p2pSource) run() {
+	defer close(source.done)
+	for {
+		if err := source.connect(); err != nil && source.ctx.Err() == nil {
+			common.Logger.Warningf("p2p peer %s error, connect again in %s, err=%s", source.addr, P2PReconnectInterval, err.Error())
+		}
+		select {
+		case <-source.ctx.Done():
+			return
+		case <-time.After(P2PReconnectInterval):
+		}
+	}
+}
+
+func (source *p2pSource) connect() error {
+	p, err := peer.NewOutboundPeer(source.peerConfig(), source.addr)
+	if err != nil {
+		return err
+	}
+	dialer := net.Dialer{Timeout: P2PRequestTimeout}
+	conn, err := dialer.DialContext(source.ctx, "tcp", source.addr)
+	if err != nil {
+		return err
+	}
+	p.AssociateConnection(conn)
+
+	source.mu.Lock()
+	source.peer = p
+	source.mu.Unlock()
+
+	disconnected := make(chan struct{})
+	go func() {
+		select {
+		case <-source.ctx.Done():
+			p.Disconnect()
+		case <-disconnected:
+		}
+	}()
+	p.WaitForDisconnect()
+	close(disconnected)
+
+	source.mu.Lock()
+	source.peer = nil
+	source.mu.Unlock()
+	return fmt.Errorf("disconnected")
+}
+
+func (source *p2pSource) peerConfig() *peer.Config {
+	return &peer.Config{
+		UserAgentName:    P2PUserAgentName,
+		UserAgentVersion: P2PUserAgentVersion,
+		ChainParams:      source.params,
+		DisableRelayTx:   true,
+		TrickleInterval:  10 * time.Second,
+		// the relayer does not listen for peers, it can not connect to itself. Without this
+		// a peer in the same process is taken for the relayer, their version nonces are shared
+		AllowSelfConns: true,
+		NewestBlock: func() (*chainhash.Hash, int32, error) {
+			source.mu.RLock()
+			defer source.mu.RUnlock()
+			tip := source.hashes[len(source.hashes)-1]
+			return &tip, int32(source.tipHeight()), nil
+		},
+		HostToNetAddress: func(host string, port uint16, services wire.ServiceFlag) (*wire.NetAddressV2, error) {
+			ips, err := net.LookupIP(host)
+			if err != nil {
+				return nil, err
+			}
+			if len(ips) == 0 {
+				return nil, fmt.Errorf("no ip of host %s", host)
+			}
+			return wire.NetAddressV2FromBytes(time.Now(), services, ips[0], port), nil
+		},
+		Listeners: peer.MessageListeners{
+			OnVerAck: func(p *peer.Peer, msg *wire.MsgVerAck) {
+				common.Logger.Infof("connected to p2p peer %s, %s", p.Addr(), p.UserAgent())
+				//new blocks are announced with headers
+				p.QueueMessage(wire.NewMsgSendHeaders(), nil)
+				source.requestHeaders(p)
+			},
+			OnHeaders: source.onHeaders,
+			OnInv: func(p *peer.Peer, msg *wire.MsgInv) {
+				for _, inv := range msg.InvList {
+					if inv.Type == wire.InvTypeBlock || inv.Type == wire.InvTypeWitnessBlock {
+						source.requestHeaders(p)
+						return
+					}
+				}
+			},
+			OnBlock: func(p *peer.Peer, msg *wire.MsgBlock, buf []byte) {
+				source.onBlock(msg)
+			},
+		},
+	}
+}
+
+func (source *p2pSource) tipHeight() int64 {
+	return source.baseHeight + int64(len(source.hashes)) - 1
+}
+
+/**
+getheaders from the tip, the locator goes back exponentially so the peer finds the fork point of a reorg
+*/
+func (source *p2pSource) requestHeaders(p *peer.Peer) {
+	source.mu.RLock()
+	msg := wire.NewMsgGetHeaders()
+	step := 1
+	for i := len(source.hashes) - 1; i > 0; i -= step {
+		msg.AddBlockLocatorHash(&source.hashes[i])
+		if len(msg.BlockLocatorHashes) >= 10 {
+			step *= 2
+		}
+		if len(msg.BlockLocatorHashes) == wire.MaxBlockLocatorsPerMsg-1 {
+			break
+		}
+	}
+	msg.AddBlockLocatorHash(&source.hashes[0])
+	source.mu.RUnlock()
+
+	//not PushGetHeadersMsg, which drops a request with the same tip as the last one
+	p.QueueMessage(msg, nil)
+}
+
+func (source *p2pSource) onHeaders(p *peer.Peer, msg *wire.MsgHeaders) {
+	if len(msg.Headers) == 0 {
+		source.setSynced()
+		return
+	}
+
+	source.mu.Lock()
+	err := source.addHeaders(msg.Headers)
+	height := source.tipHeight()
+	source.mu.Unlock()
+	if err != nil {
+		common.Logger.Warningf("invalid headers from p2p peer %s, err=%s", p.Addr(), err.Error())
+		return
+	}
+
+	if len(msg.Headers) == wire.MaxBlockHeadersPerMsg {
+		source.requestHeaders(p)
+		return
+	}
+	source.setSynced()
+	common.Logger.Debugf("headers of p2p peer %s synced, height:%d", p.Addr(), height)
+}
+
+func (source *p2pSource) setSynced() {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	source.synced = true
+}
+
+/**
+add the headers to the chain, they connect to a known block and must pass the header validation.
+Headers forking off the chain replace the blocks after the fork point if they have more work
+*/
+func (source *p2pSource) addHeaders(headers []*wire.BlockHeader) error {
+	prevHeight, ok := source.heights[headers[0].PrevBlock]
+	if !ok {
+		return fmt.Errorf("previous block %s of the headers is unknown", headers[0].PrevBlock.String())
+	}
+	for i := 1; i < len(headers); i++ {
+		if headers[i].PrevBlock != headers[i-1].BlockHash() {
+			return fmt.Errorf("header %s does not connect to the header before", headers[i].BlockHash().String())
+		}
+	}
+
+	//skip the headers already in the chain
+	skip := 0
+	for skip < len(headers) && prevHeight+int64(skip) < source.tipHeight() &&
+		source.hashes[prevHeight+int64(skip)+1-source.baseHeight] == headers[skip].BlockHash() {
+		skip++
+	}
+	headers = headers[skip:]
+	if len(headers) == 0 {
+		return nil
+	}
+
+	forkHeight := prevHeight + int64(skip)
+	if err := source.validateBranch(forkHeight, headers); err != nil {
+		return err
+	}
+	if forkHeight < source.tipHeight() {
+		work, forkWork := source.chainWork(forkHeight), headersWork(headers)
+		if forkWork.Cmp(work) <= 0 {
+			return fmt.Errorf("fork at height %d has work %s, not more than the chain %s", forkHeight, forkWork.String(), work.String())
+		}
+		common.Logger.Infof("p2p peer %s reorg, fork height:%d, old tip:%d", source.addr, forkHeight, source.tipHeight())
+		for _, hash := range source.hashes[forkHeight+1-source.baseHeight:] {
+			delete(source.heights, hash)
+			delete(source.headers, hash)
+		}
+		source.hashes = source.hashes[:forkHeight+1-source.baseHeight]
+	}
+
+	for _, header := range headers {
+		hash := header.BlockHash()
+		source.hashes = append(source.hashes, hash)
+		source.heights[hash] = source.tipHeight()
+		source.headers[hash] = header
+	}
+	return nil
+}
+
+/**
+validate the headers of a branch from forkHeight+1 in order. The headers right after a checkpoint base have
+ancestors that are not synced, their proof of work is checked only
+*/
+func (source *p2pSource) validateBranch(forkHeight int64, headers []*wire.BlockHeader) error {
+	branch := newP2PBranch(source, forkHeight, headers)
+	for i, header := range headers {
+		height := forkHeight + 1 + int64(i)
+		err := source.validator.Validate(branch, height, header)
+		if errors.Is(err, errHeaderNotSynced) {
+			err = source.validator.checkProofOfWork(header)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid header %s at height %d: %w", header.BlockHash().String(), height, err)
+		}
+	}
+	return nil
+}
+
+/**
+the work of the chain after forkHeight
+*/
+func (source *p2pSource) chainWork(forkHeight int64) *big.Int {
+	work := new(big.Int)
+	for _, hash := range source.hashes[forkHeight+1-source.baseHeight:] {
+		work.Add(work, blockchain.CalcWork(source.headers[hash].Bits))
+	}
+	return work
+}
+
+func headersWork(headers []*wire.BlockHeader) *big.Int {
+	work := new(big.Int)
+	for _, header := range headers {
+		work.Add(work, blockchain.CalcWork(header.Bits))
+	}
+	return work
+}
+
+// errHeaderNotSynced is an ancestor at or before the base of the synced headers
+var errHeaderNotSynced = errors.New("header not synced")
+
+// p2pBranch is the header chain of a p2p source up to forkHeight with a branch of new headers on it, the
+// header validator reads the ancestors of the branch from it. The caller holds the lock of the source
+type p2pBranch struct {
+	source     *p2pSource
+	forkHeight int64
+	heights    map[chainhash.Hash]int64
+	headers    map[chainhash.Hash]*wire.BlockHeader
+}
+
+func newP2PBranch(source *p2pSource, forkHeight int64, headers []*wire.BlockHeader) *p2pBranch {
+	branch := &p2pBranch{
+		source:     source,
+		forkHeight: forkHeight,
+		heights:    make(map[chainhash.Hash]int64, len(headers)),
+		headers:    make(map[chainhash.Hash]*wire.BlockHeader, len(headers)),
+	}
+	for i, header := range headers {
+		hash := header.BlockHash()
+		branch.heights[hash] = forkHeight + 1 + int64(i)
+		branch.headers[hash] = header
+	}
+	return branch
+}
+
+func (branch *p2pBranch) GetBlockHeight(hash *chainhash.Hash) (int64, error) {
+	if height, ok := branch.heights[*hash]; ok {
+		return height, nil
+	}
+	if height, ok := branch.source.heights[*hash]; ok && height <= branch.forkHeight {
+		return height, nil
+	}
+	return 0, fmt.Errorf("block %s is unknown", hash.String())
+}
+
+func (branch *p2pBranch) GetBlockHeader(hash *chainhash.Hash) (*wire.BlockHeader, error) {
+	if header, ok := branch.headers[*hash]; ok {
+		return header, nil
+	}
+	if header, ok := branch.source.headers[*hash]; ok {
+		return header, nil
+	}
+	return nil, fmt.Errorf("%w: block %s", errHeaderNotSynced, hash.String())
+}
+
+func (branch *p2pBranch) GetBlockCount() (int64, error) {
+	return branch.forkHeight + int64(len(branch.heights)), nil
+}
+
+func (branch *p2pBranch) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	return nil, errors.New("headers of a branch are read by hash")
+}
+
+func (branch *p2pBranch) GetBlockTxHashes(hash *chainhash.Hash) ([]chainhash.Hash, error) {
+	return nil, errors.New("headers only")
+}
+
+func (branch *p2pBranch) GetRawTransaction(txHash *chainhash.Hash, blockHash *chainhash.Hash) (*wire.MsgTx, error) {
+	return nil, errors.New("headers only")
+}
+
+func (branch *p2pBranch) GetBlock(hash *chainhash.Hash) (*wire.MsgBlock, error) {
+	return nil, errors.New("headers only")
+}
+
+func (source *p2pSource) onBlock(block *wire.MsgBlock) {
+	hash := block.BlockHash()
+	merkles := blockchain.BuildMerkleTreeStore(btcutil.NewBlock(block).Transactions(), false)
+	if len(merkles) == 0 || *merkles[len(merkles)-1] != block.Header.MerkleRoot {
+		common.Logger.Warningf("block %s from p2p peer %s does not match its merkle root", hash.String(), source.addr)
+		return
+	}
+
+	source.blockMu.Lock()
+	defer source.blockMu.Unlock()
+	if _, ok := source.blocks[hash]; !ok {
+		source.blocks[hash] = block
+		source.blockOrder = append(source.blockOrder, hash)
+		if len(source.blockOrder) > P2PBlockCacheSize {
+			delete(source.blocks, source.blockOrder[0])
+			source.blockOrder = source.blockOrder[1:]
+		}
+	}
+	for _, ch := range source.waiting[hash] {
+		ch <- block
+	}
+	delete(source.waiting, hash)
+}
+
+func (source *p2pSource) GetBlockCount() (int64, error) {
+	source.mu.RLock()
+	defer source.mu.RUnlock()
+	if !source.synced {
+		return 0, fmt.Errorf("headers of p2p peer %s not synced, height:%d", source.addr, source.tipHeight())
+	}
+	return source.tipHeight(), nil
+}
+
+func (source *p2pSource) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	source.mu.RLock()
+	defer source.mu.RUnlock()
+	if height < source.baseHeight || height > source.tipHeight() {
+		return nil, fmt.Errorf("no header at height %d, headers are synced from %d to %d", height, source.baseHeight, source.tipHeight())
+	}
+	hash := source.hashes[height-source.baseHeight]
+	return &hash, nil
+}
+
+func (source *p2pSource) GetBlockHeader(hash *chainhash.Hash) (*wire.BlockHeader, error) {
+	source.mu.RLock()
+	defer source.mu.RUnlock()
+	header, ok := source.headers[*hash]
+	if !ok {
+		return nil, fmt.Errorf("header of block %s is unknown", hash.String())
+	}
+	headerCopy := *header
+	return &headerCopy, nil
+}
+
+func (source *p2pSource) GetBlockHeight(hash *chainhash.Hash) (int64, error) {
+	source.mu.RLock()
+	defer source.mu.RUnlock()
+	height, ok := source.heights[*hash]
+	if !ok {
+		return 0, fmt.Errorf("block %s is unknown", hash.String())
+	}
+	return height, nil
+}
+
+func (source *p2pSource) GetBlockTxHashes(hash *chainhash.Hash) ([]chainhash.Hash, error) {
+	block, err := source.GetBlock(hash)
+	if err != nil {
+		return nil, err
+	}
+	return fillTxHashes(block.Transactions), nil
+}
+
+func (source *p2pSource) GetRawTransaction(txHash *chainhash.Hash, blockHash *chainhash.Hash) (*wire.MsgTx, error) {
+	block, err := source.GetBlock(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range block.Transactions {
+		if tx.TxHash() == *txHash {
+			return tx, nil
+		}
+	}
+	return nil, fmt.Errorf("tx %s is not in block %s", txHash.String(), blockHash.String())
+}
+
+/**
+fetch the block from the peer, with witness data if the peer serves it
+*/
+func (source *p2pSource) GetBlock(hash *chainhash.Hash) (*wire.MsgBlock, error) {
+	source.blockMu.Lock()
+	if block, ok := source.blocks[*hash]; ok {
+		source.blockMu.Unlock()
+		return block, nil
+	}
+	ch := make(chan *wire.MsgBlock, 1)
+	source.waiting[*hash] = append(source.waiting[*hash], ch)
+	source.blockMu.Unlock()
+
+	source.mu.RLock()
+	p := source.peer
+	source.mu.RUnlock()
+	if p == nil {
+		source.stopWaiting(hash, ch)
+		return nil, fmt.Errorf("p2p peer %s is not connected", source.addr)
+	}
+
+	invType := wire.InvTypeBlock
+	if p.IsWitnessEnabled() {
+		invType = wire.InvTypeWitnessBlock
+	}
+	getData := wire.NewMsgGetData()
+	getData.AddInvVect(wire.NewInvVect(invType, hash))
+	p.QueueMessage(getData, nil)
+
+	select {
+	case block := <-ch:
+		return block, nil
+	case <-time.After(source.requestTimeout):
+		source.stopWaiting(hash, ch)
+		return nil, fmt.Errorf("no block %s from p2p peer %s in %s", hash.String(), source.addr, source.requestTimeout)
+	}
+}
+
+func (source *p2pSource) stopWaiting(hash *chainhash.Hash, ch chan *wire.MsgBlock) {
+	source.blockMu.Lock()
+	defer source.blockMu.Unlock()
+	waiting := source.waiting[*hash]
+	for i := range waiting {
+		if waiting[i] == ch {
+			source.waiting[*hash] = append(waiting[:i], waiting[i+1:]...)
+			break
+		}
+	}
+	if len(source.waiting[*hash]) == 0 {
+		delete(source.waiting, *hash)
+	}
+}
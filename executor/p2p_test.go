@@ -0,0 +1,433 @@
+package executor
+
+import (
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/peer"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+// fakePeer is a regtest Bitcoin peer serving a chain of blocks, blocks[0] is the genesis block
+type fakePeer struct {
+	t        *testing.T
+	listener net.Listener
+
+	mu     sync.Mutex
+	blocks []*wire.MsgBlock
+	peers  []*peer.Peer
+	// getData counts the blocks requested
+	getData int
+	// corrupt blocks are served with a tx not matching the merkle root
+	corrupt map[chainhash.Hash]bool
+}
+
+func newFakePeer(t *testing.T, height int) *fakePeer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	fake := &fakePeer{
+		t:        t,
+		listener: listener,
+		blocks:   []*wire.MsgBlock{chaincfg.RegressionNetParams.GenesisBlock},
+		corrupt:  make(map[chainhash.Hash]bool),
+	}
+	fake.extend(0, height, 0)
+	go fake.accept()
+	return fake
+}
+
+func (fake *fakePeer) close() {
+	fake.listener.Close()
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	for _, p := range fake.peers {
+		p.Disconnect()
+	}
+}
+
+func (fake *fakePeer) addr() string {
+	return fake.listener.Addr().String()
+}
+
+/**
+replace the blocks after forkHeight with n new blocks, tag makes them differ from the replaced ones
+*/
+func (fake *fakePeer) extend(forkHeight int, n int, tag byte) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.blocks = fake.blocks[:forkHeight+1]
+	for i := 0; i < n; i++ {
+		height := len(fake.blocks)
+		coinbase := wire.NewMsgTx(2)
+		coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, 0xffffffff), []byte{0x03, byte(height), byte(height >> 8), tag}, [][]byte{make([]byte, 32)}))
+		coinbase.AddTxOut(wire.NewTxOut(5000000000, []byte{0x51}))
+		tx := wire.NewMsgTx(2)
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{byte(height)}, 0), nil, [][]byte{{tag}}))
+		tx.AddTxOut(wire.NewTxOut(int64(height), []byte{0x51}))
+
+		prev := fake.blocks[height-1]
+		prevHash := prev.BlockHash()
+		block := wire.NewMsgBlock(wire.NewBlockHeader(0x20000000, &prevHash, &chainhash.Hash{}, 0x207fffff, 0))
+		block.Header.Timestamp = prev.Header.Timestamp.Add(time.Minute)
+		block.AddTransaction(coinbase)
+		block.AddTransaction(tx)
+		merkles := blockchain.BuildMerkleTreeStore(btcutil.NewBlock(block).Transactions(), false)
+		block.Header.MerkleRoot = *merkles[len(merkles)-1]
+		solve(&block.Header)
+		fake.blocks = append(fake.blocks, block)
+	}
+}
+
+func (fake *fakePeer) block(height int) *wire.MsgBlock {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.blocks[height]
+}
+
+func (fake *fakePeer) tip() *wire.MsgBlock {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.blocks[len(fake.blocks)-1]
+}
+
+func (fake *fakePeer) requested() int {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.getData
+}
+
+/**
+announce the tip to the connected peers with a headers message, or an inv
+*/
+func (fake *fakePeer) announce(withHeaders bool) {
+	tip := fake.tip()
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	for _, p := range fake.peers {
+		if withHeaders {
+			msg := wire.NewMsgHeaders()
+			msg.AddBlockHeader(&tip.Header)
+			p.QueueMessage(msg, nil)
+		} else {
+			tipHash := tip.BlockHash()
+			msg := wire.NewMsgInv()
+			msg.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, &tipHash))
+			p.QueueMessage(msg, nil)
+		}
+	}
+}
+
+func (fake *fakePeer) accept() {
+	for {
+		conn, err := fake.listener.Accept()
+		if err != nil {
+			return
+		}
+		p := peer.NewInboundPeer(&peer.Config{
+			ChainParams: &chaincfg.RegressionNetParams,
+			Services:    wire.SFNodeNetwork | wire.SFNodeWitness,
+			// the source is in the same process, its version nonce is known
+			AllowSelfConns: true,
+			NewestBlock: func() (*chainhash.Hash, int32, error) {
+				fake.mu.Lock()
+				defer fake.mu.Unlock()
+				tipHash := fake.blocks[len(fake.blocks)-1].BlockHash()
+				return &tipHash, int32(len(fake.blocks) - 1), nil
+			},
+			Listeners: peer.MessageListeners{
+				OnGetHeaders: fake.onGetHeaders,
+				OnGetData:    fake.onGetData,
+			},
+		})
+		p.AssociateConnection(conn)
+		fake.mu.Lock()
+		fake.peers = append(fake.peers, p)
+		fake.mu.Unlock()
+	}
+}
+
+func (fake *fakePeer) onGetHeaders(p *peer.Peer, msg *wire.MsgGetHeaders) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	start := 0
+locate:
+	for _, locator := range msg.BlockLocatorHashes {
+		for height, block := range fake.blocks {
+			if block.BlockHash() == *locator {
+				start = height
+				break locate
+			}
+		}
+	}
+	headers := wire.NewMsgHeaders()
+	for height := start + 1; height < len(fake.blocks) && len(headers.Headers) < wire.MaxBlockHeadersPerMsg; height++ {
+		headers.AddBlockHeader(&fake.blocks[height].Header)
+	}
+	p.QueueMessage(headers, nil)
+}
+
+func (fake *fakePeer) onGetData(p *peer.Peer, msg *wire.MsgGetData) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	for _, inv := range msg.InvList {
+		fake.getData++
+		for _, block := range fake.blocks {
+			if block.BlockHash() != inv.Hash {
+				continue
+			}
+			if fake.corrupt[inv.Hash] {
+				corrupted := *block
+				corrupted.Transactions = []*wire.MsgTx{block.Transactions[0], block.Transactions[1].Copy()}
+				corrupted.Transactions[1].TxOut[0].Value++
+				block = &corrupted
+			}
+			encoding := wire.BaseEncoding
+			if inv.Type == wire.InvTypeWitnessBlock {
+				encoding = wire.WitnessEncoding
+			}
+			p.QueueMessageWithEncoding(block, nil, encoding)
+		}
+	}
+}
+
+func waitSynced(t *testing.T, source BTCSource, height int64) {
+	require.Eventually(t, func() bool {
+		count, err := source.GetBlockCount()
+		return err == nil && count == height
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestP2PSource_SyncHeaders(t *testing.T) {
+	fake := newFakePeer(t, wire.MaxBlockHeadersPerMsg+100)
+	defer fake.close()
+	source := newP2PSource(fake.addr(), &chaincfg.RegressionNetParams)
+	defer source.stop()
+
+	// two batches of headers
+	waitSynced(t, source, wire.MaxBlockHeadersPerMsg+100)
+
+	for _, height := range []int{0, 1, wire.MaxBlockHeadersPerMsg, wire.MaxBlockHeadersPerMsg + 100} {
+		blockHash, err := source.GetBlockHash(int64(height))
+		require.NoError(t, err)
+		require.Equal(t, fake.block(height).BlockHash(), *blockHash)
+		header, err := source.GetBlockHeader(blockHash)
+		require.NoError(t, err)
+		require.Equal(t, fake.block(height).Header, *header)
+		blockHeight, err := source.GetBlockHeight(blockHash)
+		require.NoError(t, err)
+		require.Equal(t, int64(height), blockHeight)
+	}
+	_, err := source.GetBlockHash(wire.MaxBlockHeadersPerMsg + 101)
+	require.Error(t, err)
+	// headers only, no block fetched
+	require.Equal(t, 0, fake.requested())
+}
+
+func TestP2PSource_NewBlocksAndReorg(t *testing.T) {
+	fake := newFakePeer(t, 10)
+	defer fake.close()
+	source := newP2PSource(fake.addr(), &chaincfg.RegressionNetParams)
+	defer source.stop()
+	waitSynced(t, source, 10)
+
+	fake.extend(10, 1, 0)
+	fake.announce(true)
+	waitSynced(t, source, 11)
+
+	// the blocks after height 8 are replaced by a longer fork
+	orphan := fake.block(9).BlockHash()
+	fake.extend(8, 4, 1)
+	fake.announce(false)
+	waitSynced(t, source, 12)
+
+	for height := 9; height <= 12; height++ {
+		blockHash, err := source.GetBlockHash(int64(height))
+		require.NoError(t, err)
+		require.Equal(t, fake.block(height).BlockHash(), *blockHash)
+	}
+	_, err := source.GetBlockHeight(&orphan)
+	require.Error(t, err)
+}
+
+func TestP2PSource_RelayTask(t *testing.T) {
+	fake := newFakePeer(t, 10)
+	defer fake.close()
+	executor, err := NewBTCExecutor(&config.Config{BTCConfig: config.BTCConfig{
+		Network:  config.BTCNetworkRegtest,
+		RpcAddrs: []config.BTCRpcAddrs{{Source: config.BTCSourceP2P, Host: fake.addr()}},
+	}})
+	require.NoError(t, err)
+	defer executor.Close()
+	waitSynced(t, executor.GetClient(), 10)
+
+	blockHash, err := executor.GetBlockHash(executor.GetClient(), 7)
+	require.NoError(t, err)
+	task, err := executor.GetRelayTask(executor.GetClient(), 7, blockHash)
+	require.NoError(t, err)
+	require.NotNil(t, task.PARTS)
+	// the txids and the coinbase are read from one block fetched
+	require.Equal(t, 1, fake.requested())
+
+	fromParts, err := serializeBtcLightMirror(NewBtcLightMirrorFromParts(task.PARTS))
+	require.NoError(t, err)
+	fromBlock, err := serializeBtcLightMirror(NewBtcLightMirror(fake.block(7)))
+	require.NoError(t, err)
+	require.Equal(t, fromBlock, fromParts)
+}
+
+func TestP2PSource_InvalidBlock(t *testing.T) {
+	fake := newFakePeer(t, 10)
+	defer fake.close()
+	source := newP2PSource(fake.addr(), &chaincfg.RegressionNetParams)
+	defer source.stop()
+	waitSynced(t, source, 10)
+	source.requestTimeout = 200 * time.Millisecond
+
+	blockHash := fake.block(5).BlockHash()
+	fake.mu.Lock()
+	fake.corrupt[blockHash] = true
+	fake.mu.Unlock()
+
+	// the block is dropped, the request times out
+	_, err := source.GetBlock(&blockHash)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no block")
+	require.Equal(t, 1, fake.requested())
+}
+
+func TestP2PSource_Stop(t *testing.T) {
+	fake := newFakePeer(t, 10)
+	defer fake.close()
+	source := newP2PSource(fake.addr(), &chaincfg.RegressionNetParams)
+	waitSynced(t, source, 10)
+
+	// the peer is disconnected and the connection loop has returned
+	source.stop()
+	source.mu.RLock()
+	require.Nil(t, source.peer)
+	source.mu.RUnlock()
+	source.stop()
+
+	// a source still dialing stops at once
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+	source = newP2PSource(addr, &chaincfg.RegressionNetParams)
+	start := time.Now()
+	source.stop()
+	require.Less(t, time.Since(start), time.Second)
+}
+
+/**
+a source not connected to any peer, its headers are added by hand
+*/
+func newIdleP2PSource(t *testing.T, params *chaincfg.Params) *p2pSource {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+	return newP2PSource(addr, params)
+}
+
+func (source *p2pSource) addHeadersLocked(headers []*wire.BlockHeader) error {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	return source.addHeaders(headers)
+}
+
+func (source *p2pSource) tipHash() chainhash.Hash {
+	source.mu.RLock()
+	defer source.mu.RUnlock()
+	return source.hashes[len(source.hashes)-1]
+}
+
+func TestP2PSource_InvalidHeaders(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	source := newIdleP2PSource(t, params)
+	defer source.stop()
+	chain := newHeaderChain(params.GenesisBlock.Header)
+	for i := 0; i < 3; i++ {
+		chain.add(chain.mine(params.PowLimitBits, time.Minute))
+	}
+	require.NoError(t, source.addHeadersLocked(chain.headers[1:]))
+	tip := source.tipHash()
+
+	//the last header of the batch is above its target, none of the batch is added
+	fork := newHeaderChain(params.GenesisBlock.Header)
+	fork.headers = append(fork.headers, chain.headers[1:]...)
+	for i := 0; i < 3; i++ {
+		fork.add(fork.mine(params.PowLimitBits, time.Minute))
+	}
+	header := fork.mine(params.PowLimitBits, time.Minute)
+	for blockHash := header.BlockHash(); blockchain.HashToBig(&blockHash).Cmp(params.PowLimit) <= 0; blockHash = header.BlockHash() {
+		header.Nonce++
+	}
+	err := source.addHeadersLocked(append(fork.headers[4:], header))
+	require.ErrorIs(t, err, ErrProofOfWork)
+	require.Equal(t, tip, source.tipHash())
+
+	header = fork.mine(0x201fffff, time.Minute)
+	require.ErrorIs(t, source.addHeadersLocked(append(fork.headers[4:], header)), ErrDifficulty)
+	header = fork.mine(params.PowLimitBits, -time.Hour)
+	require.ErrorIs(t, source.addHeadersLocked(append(fork.headers[4:], header)), ErrTimestamp)
+	require.Equal(t, tip, source.tipHash())
+
+	require.NoError(t, source.addHeadersLocked(fork.headers[4:]))
+	require.Equal(t, fork.headers[6].BlockHash(), source.tipHash())
+}
+
+func TestP2PSource_ForkWork(t *testing.T) {
+	//retarget every 4 blocks, on the regtest proof of work limit
+	params := chaincfg.RegressionNetParams
+	params.Net = wire.MainNet
+	params.ReduceMinDifficulty = false
+	params.TargetTimespan = 4 * params.TargetTimePerBlock
+	source := newIdleP2PSource(t, &params)
+	defer source.stop()
+
+	//blocks came fast, the headers from height 4 have 4 times the work
+	target := new(big.Int).Div(params.PowLimit, big.NewInt(params.RetargetAdjustmentFactor))
+	chain := newHeaderChain(params.GenesisBlock.Header)
+	for height := 1; height <= 5; height++ {
+		bits := params.PowLimitBits
+		if height >= 4 {
+			bits = blockchain.BigToCompact(target)
+		}
+		chain.add(chain.mine(bits, time.Second))
+	}
+	require.NoError(t, source.addHeadersLocked(chain.headers[1:]))
+
+	//a longer fork of blocks at the minimum difficulty has less work
+	fork := newHeaderChain(params.GenesisBlock.Header)
+	for height := 1; height <= 8; height++ {
+		fork.add(fork.mine(params.PowLimitBits, time.Hour))
+	}
+	err := source.addHeadersLocked(fork.headers[1:])
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not more than the chain")
+	require.Equal(t, chain.headers[5].BlockHash(), source.tipHash())
+	_, err = source.GetBlockHeight(&fork.headers[1].PrevBlock)
+	require.NoError(t, err)
+
+	//the fork gets more work than the chain
+	for height := 9; height <= 12; height++ {
+		fork.add(fork.mine(params.PowLimitBits, time.Hour))
+	}
+	require.NoError(t, source.addHeadersLocked(fork.headers[1:]))
+	require.Equal(t, fork.headers[12].BlockHash(), source.tipHash())
+	orphan := chain.headers[5].BlockHash()
+	_, err = source.GetBlockHeight(&orphan)
+	require.Error(t, err)
+}
@@ -0,0 +1,133 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// BlockHashAnswer is the block hash a btc endpoint returned for a height, or its error
+type BlockHashAnswer struct {
+	Provider  string
+	BlockHash *chainhash.Hash
+	Err       error
+}
+
+func (answer BlockHashAnswer) String() string {
+	if answer.Err != nil {
+		return fmt.Sprintf("%s: error %s", answer.Provider, answer.Err.Error())
+	}
+	return fmt.Sprintf("%s: %s", answer.Provider, answer.BlockHash.String())
+}
+
+// QuorumResult is the block hash of a height enough btc endpoints agree on
+type QuorumResult struct {
+	Height    int64
+	BlockHash *chainhash.Hash
+	// Client returned BlockHash, it is the current client if that one agrees
+	Client  BTCSource
+	Answers []BlockHashAnswer
+}
+
+func (result *QuorumResult) Disagreed() bool {
+	return answersDisagree(result.Answers)
+}
+
+// QuorumError means fewer than Quorum btc endpoints agree on the block hash of a height
+type QuorumError struct {
+	Height  int64
+	Quorum  int
+	Answers []BlockHashAnswer
+}
+
+func (e *QuorumError) Error() string {
+	return fmt.Sprintf("no quorum of %d on the block hash at height %d, %s", e.Quorum, e.Height, FormatAnswers(e.Answers))
+}
+
+/**
+a lagging endpoint fails to answer, only different block hashes are a disagreement
+*/
+func (e *QuorumError) Disagreed() bool {
+	return answersDisagree(e.Answers)
+}
+
+func answersDisagree(answers []BlockHashAnswer) bool {
+	var first *chainhash.Hash
+	for _, answer := range answers {
+		if answer.Err != nil {
+			continue
+		}
+		if first == nil {
+			first = answer.BlockHash
+		} else if !answer.BlockHash.IsEqual(first) {
+			return true
+		}
+	}
+	return false
+}
+
+func FormatAnswers(answers []BlockHashAnswer) string {
+	answerStrs := make([]string, 0, len(answers))
+	for _, answer := range answers {
+		answerStrs = append(answerStrs, answer.String())
+	}
+	return strings.Join(answerStrs, ", ")
+}
+
+/**
+ask every btc endpoint for the block hash of height, at least btc_config.quorum of them must return the same one
+*/
+func (executor *BTCExecutor) GetQuorumBlockHash(height int64) (*QuorumResult, error) {
+	executor.mutex.RLock()
+	btcClients := executor.BTCClients
+	current := btcClients[executor.clientIdx]
+	executor.mutex.RUnlock()
+
+	answers := make([]BlockHashAnswer, len(btcClients))
+	var wg sync.WaitGroup
+	for i, btcClient := range btcClients {
+		wg.Add(1)
+		go func(i int, btcClient *BTCClient) {
+			defer wg.Done()
+			blockHash, err := btcClient.BTCClient.GetBlockHash(height)
+			answers[i] = BlockHashAnswer{Provider: btcClient.Provider, BlockHash: blockHash, Err: err}
+		}(i, btcClient)
+	}
+	wg.Wait()
+
+	votes := make(map[chainhash.Hash]int)
+	for _, answer := range answers {
+		if answer.Err == nil {
+			votes[*answer.BlockHash]++
+		}
+	}
+	//a split where two block hashes both reach the quorum is no quorum either
+	quorum := executor.Config.BTCConfig.Quorum
+	var agreed *chainhash.Hash
+	for blockHash, count := range votes {
+		if count < quorum {
+			continue
+		}
+		if agreed != nil {
+			return nil, &QuorumError{Height: height, Quorum: quorum, Answers: answers}
+		}
+		blockHash := blockHash
+		agreed = &blockHash
+	}
+	if agreed == nil {
+		return nil, &QuorumError{Height: height, Quorum: quorum, Answers: answers}
+	}
+
+	result := &QuorumResult{Height: height, BlockHash: agreed, Answers: answers}
+	for i, answer := range answers {
+		if answer.Err != nil || !answer.BlockHash.IsEqual(agreed) {
+			continue
+		}
+		if result.Client == nil || btcClients[i] == current {
+			result.Client = btcClients[i].BTCClient
+		}
+	}
+	return result, nil
+}
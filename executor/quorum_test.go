@@ -0,0 +1,66 @@
+package executor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+func newQuorumExecutor(quorum int, clientIdx int, chains ...*headerChain) *BTCExecutor {
+	btcClients := make([]*BTCClient, 0, len(chains))
+	for i, chain := range chains {
+		btcClients = append(btcClients, &BTCClient{BTCClient: chain, Provider: string(rune('a' + i))})
+	}
+	return &BTCExecutor{
+		clientIdx:  clientIdx,
+		BTCClients: btcClients,
+		Config:     &config.Config{BTCConfig: config.BTCConfig{Quorum: quorum}},
+	}
+}
+
+func TestBTCExecutor_GetQuorumBlockHash(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	chain := newHeaderChain(params.GenesisBlock.Header)
+	chain.add(chain.mine(params.PowLimitBits, time.Minute))
+	//lagging is one block behind, fork has another block at height 2
+	lagging := &headerChain{headers: chain.headers[:2:2]}
+	fork := &headerChain{headers: chain.headers[:2:2]}
+	chain.add(chain.mine(params.PowLimitBits, time.Minute))
+	fork.add(fork.mine(params.PowLimitBits, 2*time.Minute))
+	blockHash := chain.headers[2].BlockHash()
+	forkHash := fork.headers[2].BlockHash()
+
+	result, err := newQuorumExecutor(2, 0, chain, chain, lagging).GetQuorumBlockHash(2)
+	require.NoError(t, err)
+	require.Equal(t, blockHash, *result.BlockHash)
+	require.Error(t, result.Answers[2].Err)
+	require.False(t, result.Disagreed())
+
+	//the current client answers another block hash, the block is fetched from an agreeing one
+	result, err = newQuorumExecutor(2, 1, chain, fork, chain).GetQuorumBlockHash(2)
+	require.NoError(t, err)
+	require.Equal(t, blockHash, *result.BlockHash)
+	require.True(t, result.Client == chain)
+	require.True(t, result.Disagreed())
+	require.Equal(t, "b: "+forkHash.String(), result.Answers[1].String())
+
+	//a lagging endpoint is no disagreement
+	_, err = newQuorumExecutor(2, 0, chain, lagging).GetQuorumBlockHash(2)
+	var quorumErr *QuorumError
+	require.ErrorAs(t, err, &quorumErr)
+	require.False(t, quorumErr.Disagreed())
+
+	_, err = newQuorumExecutor(2, 0, chain, fork, lagging).GetQuorumBlockHash(2)
+	require.ErrorAs(t, err, &quorumErr)
+	require.True(t, quorumErr.Disagreed())
+	require.Contains(t, err.Error(), "a: "+blockHash.String())
+	require.Contains(t, err.Error(), "b: "+forkHash.String())
+
+	//two block hashes reaching the quorum
+	_, err = newQuorumExecutor(2, 0, chain, fork, chain, fork).GetQuorumBlockHash(2)
+	require.ErrorAs(t, err, &quorumErr)
+}
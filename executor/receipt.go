@@ -0,0 +1,327 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	relayercommon "github.com/coredao-org/btc-relayer/common"
+)
+
+// TxOutcome is how a relay transaction ended
+type TxOutcome int
+
+const (
+	// TxIncluded means the tx is included with the required confirmations and the header is stored
+	TxIncluded TxOutcome = iota
+	// TxReverted means the tx failed or the light client rejected the header
+	TxReverted
+	// TxDropped means the tx left the mempool without being included, or its nonce was used by another tx
+	TxDropped
+	// TxSuperseded means another relayer stored the header first
+	TxSuperseded
+	// TxTimedOut means confirm_timeout_second passed before any of the above
+	TxTimedOut
+)
+
+func (outcome TxOutcome) String() string {
+	switch outcome {
+	case TxIncluded:
+		return "included"
+	case TxReverted:
+		return "reverted"
+	case TxDropped:
+		return "dropped"
+	case TxSuperseded:
+		return "superseded"
+	case TxTimedOut:
+		return "timed out"
+	}
+	return fmt.Sprintf("unknown(%d)", int(outcome))
+}
+
+// RelayResult is the outcome of a relay transaction
+type RelayResult struct {
+	Outcome TxOutcome
+	// TxHash is the tx of the chain that was included, or the latest one
+	TxHash common.Hash
+	// TxHashes are the relay tx and its replacements, in the order they were sent
+	TxHashes []common.Hash
+	Receipt  *types.Receipt
+	// OutOfGas is set if a reverted tx used all its gas, it can be retried with more gas
+	OutOfGas bool
+	// Submitter is who stored the header, for a superseded tx
+	Submitter string
+	// Err is the RelayError of a reverted tx
+	Err error
+}
+
+const (
+	ReceiptPollInterval = 500 * time.Millisecond
+)
+
+// ReceiptTracker waits for the receipt of a relay transaction until an outcome is known or the timeout passes.
+// A tx pending for stuckBlocks Core blocks is replaced with a bumped fee, every tx of the replacement chain is tracked
+type ReceiptTracker struct {
+	executor      *COREExecutor
+	timeout       time.Duration
+	confirmations uint64
+	stuckBlocks   uint64
+	droppedBlocks uint64
+	pollInterval  time.Duration
+	// onReplace is called with every replacement tx sent, to record it before the outcome is known
+	onReplace func(replacement *types.Transaction)
+}
+
+func (executor *COREExecutor) newReceiptTracker() *ReceiptTracker {
+	return &ReceiptTracker{
+		executor:      executor,
+		timeout:       executor.cfg.COREConfig.GetConfirmTimeout(),
+		confirmations: executor.cfg.COREConfig.GetConfirmations(),
+		stuckBlocks:   executor.cfg.COREConfig.StuckTxBlocks,
+		droppedBlocks: executor.cfg.COREConfig.GetDroppedTxBlocks(),
+		pollInterval:  ReceiptPollInterval,
+	}
+}
+
+// relayTxChain is a relay tx and its replacements, all with the same nonce
+type relayTxChain struct {
+	txs []*types.Transaction
+	// Core block height when the latest tx was sent, 0 until known
+	sentAt uint64
+	// Core block height when the latest tx was first unknown to the provider, unknown is unset once it is found
+	unknownSince uint64
+	unknown      bool
+	// no more replacement once the fee ceiling is reached
+	ceilingReached bool
+}
+
+func (chain *relayTxChain) latest() *types.Transaction {
+	return chain.txs[len(chain.txs)-1]
+}
+
+func (chain *relayTxChain) hashes() []common.Hash {
+	hashes := make([]common.Hash, 0, len(chain.txs))
+	for _, tx := range chain.txs {
+		hashes = append(hashes, tx.Hash())
+	}
+	return hashes
+}
+
+func (chain *relayTxChain) result(outcome TxOutcome, tx *types.Transaction) *RelayResult {
+	if tx == nil {
+		tx = chain.latest()
+	}
+	return &RelayResult{Outcome: outcome, TxHash: tx.Hash(), TxHashes: chain.hashes()}
+}
+
+/**
+wait for the outcome of tx, which relays btcBlockHash
+*/
+func (tracker *ReceiptTracker) Wait(ctx context.Context, btcBlockHash *chainhash.Hash, tx *types.Transaction) *RelayResult {
+	return tracker.waitChain(ctx, btcBlockHash, []*types.Transaction{tx})
+}
+
+/**
+wait for the outcome of a relay tx and the replacements of it already sent, txs are in the order they were sent
+*/
+func (tracker *ReceiptTracker) waitChain(ctx context.Context, btcBlockHash *chainhash.Hash, txs []*types.Transaction) *RelayResult {
+	ctx, cancel := context.WithTimeout(ctx, tracker.timeout)
+	defer cancel()
+
+	chain := &relayTxChain{txs: txs}
+	var lastReceipt *types.Receipt
+	for {
+		result, receipt := tracker.poll(ctx, btcBlockHash, chain)
+		if result != nil {
+			return result
+		}
+		if receipt != nil {
+			lastReceipt = receipt
+		}
+
+		select {
+		case <-ctx.Done():
+			relayercommon.Logger.Warningf("no outcome of tx %s in %s", chain.latest().Hash().String(), tracker.timeout)
+			result := chain.result(TxTimedOut, nil)
+			result.Receipt = lastReceipt
+			return result
+		case <-time.After(tracker.pollInterval):
+		}
+	}
+}
+
+/**
+check the txs once, return nil if there is no outcome yet, with the receipt waiting for confirmations
+*/
+func (tracker *ReceiptTracker) poll(ctx context.Context, btcBlockHash *chainhash.Hash, chain *relayTxChain) (*RelayResult, *types.Receipt) {
+	executor := tracker.executor
+	client := executor.GetClient()
+
+	//any tx of the chain may be included
+	tx, receipt, err := tracker.findReceipt(ctx, chain)
+	if err != nil {
+		relayercommon.Logger.Warningf("query receipt of tx %s error, err=%s", chain.latest().Hash().String(), err.Error())
+		return nil, nil
+	}
+	if receipt != nil {
+		return tracker.checkReceipt(ctx, btcBlockHash, chain, tx, receipt), receipt
+	}
+
+	//not included yet, the header may be stored by a competitor
+	relayed, err := executor.CheckBlockRelayed(ctx, btcBlockHash)
+	if err == nil && relayed {
+		submitter, err := executor.QuerySubmitters(ctx, btcBlockHash)
+		if err == nil && submitter != "" && common.HexToAddress(submitter) != executor.txSender {
+			relayercommon.Logger.Infof("block %s is relayed by:[%s]", btcBlockHash.String(), submitter)
+			result := chain.result(TxSuperseded, nil)
+			result.Submitter = submitter
+			return result, nil
+		}
+	}
+
+	latest := chain.latest()
+	_, _, err = client.TransactionByHash(ctx, latest.Hash())
+	if errors.Is(err, ethereum.NotFound) {
+		if tracker.unknownForBlocks(ctx, chain) {
+			relayercommon.Logger.Infof("tx %s is unknown to provider for %d blocks, dropped", latest.Hash().String(), tracker.droppedBlocks)
+			return chain.result(TxDropped, nil), nil
+		}
+	} else if err == nil {
+		chain.unknown = false
+	}
+
+	//another tx of the relayer account took the nonce
+	nonce, err := client.NonceAt(ctx, executor.txSender, nil)
+	if err == nil && nonce > latest.Nonce() {
+		if _, receipt, err := tracker.findReceipt(ctx, chain); err == nil && receipt == nil {
+			relayercommon.Logger.Infof("nonce %d of tx %s is used by another tx, dropped", latest.Nonce(), latest.Hash().String())
+			return chain.result(TxDropped, nil), nil
+		}
+		return nil, nil
+	}
+
+	tracker.replaceIfStuck(ctx, chain)
+	return nil, nil
+}
+
+/**
+whether the latest tx is unknown to the provider for droppedBlocks Core blocks, counted from the first poll it is unknown.
+Counting blocks rather than polls tolerates the backends of a load balanced provider having different mempools
+*/
+func (tracker *ReceiptTracker) unknownForBlocks(ctx context.Context, chain *relayTxChain) bool {
+	head, err := tracker.executor.GetClient().BlockNumber(ctx)
+	if err != nil {
+		return false
+	}
+	if !chain.unknown {
+		chain.unknown = true
+		chain.unknownSince = head
+		return false
+	}
+	return head >= chain.unknownSince+tracker.droppedBlocks
+}
+
+func (tracker *ReceiptTracker) findReceipt(ctx context.Context, chain *relayTxChain) (*types.Transaction, *types.Receipt, error) {
+	client := tracker.executor.GetClient()
+	for i := len(chain.txs) - 1; i >= 0; i-- {
+		receipt, err := client.TransactionReceipt(ctx, chain.txs[i].Hash())
+		if err == nil {
+			return chain.txs[i], receipt, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, nil
+}
+
+/**
+replace the latest tx with a bumped fee if it is pending for stuckBlocks Core blocks
+*/
+func (tracker *ReceiptTracker) replaceIfStuck(ctx context.Context, chain *relayTxChain) {
+	if tracker.stuckBlocks == 0 || chain.ceilingReached {
+		return
+	}
+	head, err := tracker.executor.GetClient().BlockNumber(ctx)
+	if err != nil {
+		return
+	}
+	if chain.sentAt == 0 {
+		chain.sentAt = head
+		return
+	}
+	if head < chain.sentAt+tracker.stuckBlocks {
+		return
+	}
+
+	latest := chain.latest()
+	replacement, err := tracker.executor.replaceTx(ctx, latest)
+	if err != nil {
+		if errors.Is(err, ErrFeeCeiling) {
+			chain.ceilingReached = true
+		}
+		relayercommon.Logger.Warningf("replace stuck tx %s error, err=%s", latest.Hash().String(), err.Error())
+		return
+	}
+	relayercommon.Logger.Infof("tx %s is pending for %d blocks, replaced by tx %s, nonce:%d",
+		latest.Hash().String(), head-chain.sentAt, replacement.Hash().String(), replacement.Nonce())
+	chain.txs = append(chain.txs, replacement)
+	chain.sentAt = head
+	chain.unknown = false
+	if tracker.onReplace != nil {
+		tracker.onReplace(replacement)
+	}
+}
+
+func (tracker *ReceiptTracker) checkReceipt(ctx context.Context, btcBlockHash *chainhash.Hash, chain *relayTxChain, tx *types.Transaction, receipt *types.Receipt) *RelayResult {
+	executor := tracker.executor
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		if tx.Gas() == receipt.GasUsed {
+			result := chain.result(TxReverted, tx)
+			result.Receipt = receipt
+			result.OutOfGas = true
+			return result
+		}
+		return tracker.rejected(ctx, btcBlockHash, chain, tx, receipt, executor.relayErrorOfRevert(btcBlockHash, tx, receipt))
+	}
+
+	//the light client reports a rejected header by the return code of StoreHeader
+	if relayErr := executor.relayErrorOfReceipt(btcBlockHash, receipt); relayErr != nil {
+		return tracker.rejected(ctx, btcBlockHash, chain, tx, receipt, relayErr)
+	}
+
+	head, err := executor.GetClient().BlockNumber(ctx)
+	if err != nil || receipt.BlockNumber == nil {
+		return nil
+	}
+	included := receipt.BlockNumber.Uint64()
+	if head < included || head-included+1 < tracker.confirmations {
+		return nil
+	}
+	result := chain.result(TxIncluded, tx)
+	result.Receipt = receipt
+	return result
+}
+
+/**
+a header rejected as already existing was stored by a competitor
+*/
+func (tracker *ReceiptTracker) rejected(ctx context.Context, btcBlockHash *chainhash.Hash, chain *relayTxChain, tx *types.Transaction, receipt *types.Receipt, relayErr *RelayError) *RelayResult {
+	var result *RelayResult
+	if errors.Is(relayErr, ErrBlockAlreadyExists) {
+		result = chain.result(TxSuperseded, tx)
+		result.Submitter, _ = tracker.executor.QuerySubmitters(ctx, btcBlockHash)
+	} else {
+		result = chain.result(TxReverted, tx)
+		result.Err = relayErr
+	}
+	result.Receipt = receipt
+	return result
+}
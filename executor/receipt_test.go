@@ -0,0 +1,257 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+func newTestReceiptTracker(t *testing.T, confirmations uint64) (*fakeEth, *ReceiptTracker, func()) {
+	return newTestReceiptTrackerWithConfig(t, config.COREConfig{GasLimit: 4700000, Confirmations: confirmations})
+}
+
+func newTestReceiptTrackerWithConfig(t *testing.T, coreCfg config.COREConfig) (*fakeEth, *ReceiptTracker, func()) {
+	eth, server := newFakeCoreNode(t)
+	executor := newTestCOREExecutor(t, server.URL, coreCfg)
+	eth.call = lightClientCall(t, false, common.Address{})
+
+	tracker := executor.newReceiptTracker()
+	tracker.timeout = time.Second
+	tracker.pollInterval = 10 * time.Millisecond
+	return eth, tracker, server.Close
+}
+
+func signedTestTx(t *testing.T) *types.Transaction {
+	privKey, err := crypto.HexToECDSA(testPrivateKey)
+	require.NoError(t, err)
+	signed, err := NewLocalSigner(privKey).SignTx(context.Background(), newTestTx(), big.NewInt(1116))
+	require.NoError(t, err)
+	return signed
+}
+
+func testBtcHash(t *testing.T) *chainhash.Hash {
+	btcHash, err := chainhash.NewHashFromStr(testBtcBlockHash)
+	require.NoError(t, err)
+	return btcHash
+}
+
+func TestReceiptTracker_Included(t *testing.T) {
+	eth, tracker, stop := newTestReceiptTracker(t, 3)
+	defer stop()
+
+	tx := signedTestTx(t)
+	eth.setTx(tx, &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(100), GasUsed: 50000})
+	eth.setHead(102)
+
+	result := tracker.Wait(context.Background(), testBtcHash(t), tx)
+	require.Equal(t, TxIncluded, result.Outcome)
+	require.Equal(t, tx.Hash(), result.TxHash)
+	require.NotNil(t, result.Receipt)
+}
+
+func TestReceiptTracker_WaitConfirmations(t *testing.T) {
+	eth, tracker, stop := newTestReceiptTracker(t, 3)
+	defer stop()
+	tracker.timeout = 100 * time.Millisecond
+
+	tx := signedTestTx(t)
+	eth.setTx(tx, &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(100), GasUsed: 50000})
+	eth.setHead(101)
+
+	// two confirmations only
+	result := tracker.Wait(context.Background(), testBtcHash(t), tx)
+	require.Equal(t, TxTimedOut, result.Outcome)
+	require.NotNil(t, result.Receipt)
+}
+
+func TestReceiptTracker_Reverted(t *testing.T) {
+	eth, tracker, stop := newTestReceiptTracker(t, 1)
+	defer stop()
+
+	btcHash := testBtcHash(t)
+	tx := signedTestTx(t)
+	eth.setTx(tx, &types.Receipt{
+		Status:      types.ReceiptStatusSuccessful,
+		BlockNumber: big.NewInt(100),
+		GasUsed:     50000,
+		Logs:        []*types.Log{storeHeaderLog(common.Hash(*RevertHash(btcHash)), 10030)},
+	})
+	eth.setHead(100)
+
+	result := tracker.Wait(context.Background(), btcHash, tx)
+	require.Equal(t, TxReverted, result.Outcome)
+	require.False(t, result.OutOfGas)
+	require.True(t, errors.Is(result.Err, ErrNoPrevBlock))
+}
+
+func TestReceiptTracker_OutOfGas(t *testing.T) {
+	eth, tracker, stop := newTestReceiptTracker(t, 1)
+	defer stop()
+
+	tx := signedTestTx(t)
+	eth.setTx(tx, &types.Receipt{Status: types.ReceiptStatusFailed, BlockNumber: big.NewInt(100), GasUsed: tx.Gas()})
+	eth.setHead(100)
+
+	result := tracker.Wait(context.Background(), testBtcHash(t), tx)
+	require.Equal(t, TxReverted, result.Outcome)
+	require.True(t, result.OutOfGas)
+}
+
+func TestReceiptTracker_Superseded(t *testing.T) {
+	eth, tracker, stop := newTestReceiptTracker(t, 1)
+	defer stop()
+
+	competitor := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	eth.call = lightClientCall(t, true, competitor)
+	tx := signedTestTx(t)
+	eth.setTx(tx, nil)
+
+	result := tracker.Wait(context.Background(), testBtcHash(t), tx)
+	require.Equal(t, TxSuperseded, result.Outcome)
+	require.Equal(t, competitor, common.HexToAddress(result.Submitter))
+}
+
+// mine advances the head of the fake node by a block every interval until the returned func is called
+func mine(eth *fakeEth, interval time.Duration) func() {
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(interval):
+				eth.setHead(uint64(eth.BlockNumber()) + 1)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func TestReceiptTracker_Dropped(t *testing.T) {
+	eth, tracker, stop := newTestReceiptTrackerWithConfig(t, config.COREConfig{GasLimit: 4700000, DroppedTxBlocks: 3})
+	defer stop()
+	eth.setHead(100)
+
+	// unknown to the node while no block is mined is not dropped yet
+	tracker.timeout = 100 * time.Millisecond
+	tx := signedTestTx(t)
+	result := tracker.Wait(context.Background(), testBtcHash(t), tx)
+	require.Equal(t, TxTimedOut, result.Outcome)
+
+	// unknown for dropped_tx_blocks blocks
+	defer mine(eth, 20*time.Millisecond)()
+	tracker.timeout = time.Second
+	result = tracker.Wait(context.Background(), testBtcHash(t), tx)
+	require.Equal(t, TxDropped, result.Outcome)
+}
+
+func TestReceiptTracker_BrieflyUnknown(t *testing.T) {
+	eth, tracker, stop := newTestReceiptTrackerWithConfig(t, config.COREConfig{GasLimit: 4700000, DroppedTxBlocks: 5})
+	defer stop()
+	eth.setHead(100)
+
+	// a backend of a load balanced provider does not know the tx for many polls and a block, then it is included
+	tx := signedTestTx(t)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		eth.setHead(101)
+		time.Sleep(100 * time.Millisecond)
+		eth.setTx(tx, &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(102), GasUsed: 50000})
+		eth.setHead(102)
+	}()
+	result := tracker.Wait(context.Background(), testBtcHash(t), tx)
+	require.Equal(t, TxIncluded, result.Outcome)
+	require.Equal(t, tx.Hash(), result.TxHash)
+}
+
+func TestReceiptTracker_NonceUsed(t *testing.T) {
+	eth, tracker, stop := newTestReceiptTracker(t, 1)
+	defer stop()
+
+	tx := signedTestTx(t)
+	eth.setTx(tx, nil)
+	eth.nonce = tx.Nonce() + 1
+
+	result := tracker.Wait(context.Background(), testBtcHash(t), tx)
+	require.Equal(t, TxDropped, result.Outcome)
+}
+
+func TestReceiptTracker_Pending(t *testing.T) {
+	eth, tracker, stop := newTestReceiptTracker(t, 1)
+	defer stop()
+	tracker.timeout = 100 * time.Millisecond
+
+	tx := signedTestTx(t)
+	eth.setTx(tx, nil)
+	eth.nonce = tx.Nonce()
+
+	result := tracker.Wait(context.Background(), testBtcHash(t), tx)
+	require.Equal(t, TxTimedOut, result.Outcome)
+	require.Nil(t, result.Receipt)
+}
+
+func TestReceiptTracker_ReplaceStuckTx(t *testing.T) {
+	eth, tracker, stop := newTestReceiptTrackerWithConfig(t, config.COREConfig{
+		GasLimit:      4700000,
+		StuckTxBlocks: 2,
+		MaxGasPrice:   100000000000,
+	})
+	defer stop()
+
+	tx := signedTestTx(t)
+	eth.setTx(tx, nil)
+	eth.nonce = tx.Nonce()
+	eth.setHead(100)
+	// the replacement is included at once
+	eth.onSend = func(sent *types.Transaction) {
+		eth.setTx(sent, &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(102), GasUsed: 50000})
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		eth.setHead(102)
+	}()
+
+	result := tracker.Wait(context.Background(), testBtcHash(t), tx)
+	require.Equal(t, TxIncluded, result.Outcome)
+	require.Len(t, eth.sent, 1)
+	replacement := eth.sent[0]
+	require.Equal(t, tx.Nonce(), replacement.Nonce())
+	require.Equal(t, tx.Data(), replacement.Data())
+	require.Equal(t, big.NewInt(DefaultGasPrice*110/100), replacement.GasPrice())
+	require.Equal(t, replacement.Hash(), result.TxHash)
+	require.Equal(t, []common.Hash{tx.Hash(), replacement.Hash()}, result.TxHashes)
+}
+
+func TestReceiptTracker_ReplaceUpToCeiling(t *testing.T) {
+	eth, tracker, stop := newTestReceiptTrackerWithConfig(t, config.COREConfig{
+		GasLimit:      4700000,
+		StuckTxBlocks: 1,
+		MaxGasPrice:   DefaultGasPrice + 1,
+	})
+	defer stop()
+	tracker.timeout = 200 * time.Millisecond
+
+	tx := signedTestTx(t)
+	eth.setTx(tx, nil)
+	eth.nonce = tx.Nonce()
+	eth.setHead(100)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		eth.setHead(110)
+	}()
+
+	result := tracker.Wait(context.Background(), testBtcHash(t), tx)
+	require.Equal(t, TxTimedOut, result.Outcome)
+	require.Empty(t, eth.sent)
+	require.Equal(t, []common.Hash{tx.Hash()}, result.TxHashes)
+}
@@ -0,0 +1,235 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	relayercommon "github.com/coredao-org/btc-relayer/common"
+	"github.com/coredao-org/btc-relayer/model"
+)
+
+// the relay state store records every relay attempt, all methods are no-ops without a db.
+// A store error is logged and never fails a relay
+
+func newRelayAttempt(task *relayercommon.Task, bts []byte, gasLimit uint64, outcome string) *model.RelayAttempt {
+	attempt := &model.RelayAttempt{
+		Height:     task.Height,
+		BlockHash:  task.BlockHash.String(),
+		MirrorSize: len(bts),
+		GasLimit:   gasLimit,
+		Outcome:    outcome,
+	}
+	if header := task.Header(); header != nil {
+		attempt.BlockTime = header.Timestamp
+	}
+	return attempt
+}
+
+/**
+insert attempt, its ID is set unless the store failed
+*/
+func (executor *COREExecutor) recordAttempt(attempt *model.RelayAttempt) {
+	if executor.db == nil {
+		return
+	}
+	if err := executor.db.Create(attempt).Error; err != nil {
+		relayercommon.Logger.Errorf("record relay attempt error, blockHash:%s, err=%s", attempt.BlockHash, err.Error())
+	}
+}
+
+func (executor *COREExecutor) recordFailedAttempt(task *relayercommon.Task, bts []byte, gasLimit uint64, outcome string, err error) {
+	attempt := newRelayAttempt(task, bts, gasLimit, outcome)
+	attempt.Error = err.Error()
+	executor.recordAttempt(attempt)
+}
+
+/**
+record a sent relay tx as pending, return the attempt ID or 0 if it is not recorded
+*/
+func (executor *COREExecutor) recordSentAttempt(task *relayercommon.Task, bts []byte, tx *types.Transaction) int64 {
+	attempt := newRelayAttempt(task, bts, tx.Gas(), model.OutcomePending)
+	attempt.TxHash = tx.Hash().String()
+	attempt.TxHashes = attempt.TxHash
+	attempt.Nonce = tx.Nonce()
+	executor.recordAttempt(attempt)
+	return attempt.ID
+}
+
+/**
+add the replacement of a pending relay tx to the tx hashes of its attempt
+*/
+func (executor *COREExecutor) recordReplacement(attemptID int64, replacement *types.Transaction) {
+	if executor.db == nil || attemptID == 0 {
+		return
+	}
+	var attempt model.RelayAttempt
+	err := executor.db.First(&attempt, attemptID).Error
+	if err == nil {
+		txHashes := append(attempt.SentTxHashes(), replacement.Hash().String())
+		err = executor.db.Model(&attempt).Update("tx_hashes", strings.Join(txHashes, ",")).Error
+	}
+	if err != nil {
+		relayercommon.Logger.Errorf("record replacement tx %s of relay attempt %d error, err=%s", replacement.Hash().String(), attemptID, err.Error())
+	}
+}
+
+/**
+update the attempt of the relay tx sent with the outcome of result, the gas and fee of an included tx are recorded too
+*/
+func (executor *COREExecutor) settleAttempt(attemptID int64, sent *types.Transaction, result *RelayResult) {
+	if executor.db == nil || attemptID == 0 {
+		return
+	}
+	won := result.Outcome == TxIncluded
+	updates := map[string]interface{}{
+		"tx_hash": result.TxHash.String(),
+		"outcome": result.Outcome.String(),
+		"won":     won,
+	}
+	if won {
+		updates["submitter"] = executor.txSender.String()
+	} else if result.Submitter != "" {
+		updates["submitter"] = result.Submitter
+	}
+	if result.Err != nil {
+		updates["error"] = result.Err.Error()
+	}
+	if result.Receipt != nil {
+		updates["gas_used"] = result.Receipt.GasUsed
+		if fee, includedAt, err := executor.feeOfReceipt(sent, result); err != nil {
+			relayercommon.Logger.Warningf("get fee of tx %s error, err=%s", result.TxHash.String(), err.Error())
+		} else {
+			updates["fee_paid"] = fee.String()
+			updates["included_at"] = includedAt
+		}
+	}
+
+	err := executor.db.Model(&model.RelayAttempt{ID: attemptID}).Updates(updates).Error
+	if err != nil {
+		relayercommon.Logger.Errorf("settle relay attempt %d error, err=%s", attemptID, err.Error())
+	}
+}
+
+/**
+close the relay state store, after the last relay tx is settled
+*/
+func (executor *COREExecutor) Close() error {
+	if executor.db == nil {
+		return nil
+	}
+	return executor.db.Close()
+}
+
+/**
+the fee paid by the included tx of result, which is sent or one of its replacements, and the time of its block
+*/
+func (executor *COREExecutor) feeOfReceipt(sent *types.Transaction, result *RelayResult) (*big.Int, time.Time, error) {
+	tx := sent
+	if sent.Hash() != result.TxHash {
+		var err error
+		tx, _, err = executor.TransactionByHash(result.TxHash)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+	header, err := executor.GetClient().HeaderByNumber(context.Background(), result.Receipt.BlockNumber)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return relayFee(tx, result.Receipt.GasUsed, header.BaseFee), time.Unix(int64(header.Time), 0), nil
+}
+
+/**
+gas used times the price the tx paid, a dynamic fee tx pays the base fee and its effective tip
+*/
+func relayFee(tx *types.Transaction, gasUsed uint64, baseFee *big.Int) *big.Int {
+	gasPrice := tx.GasPrice()
+	if baseFee != nil {
+		gasPrice = new(big.Int).Add(baseFee, tx.EffectiveGasTipValue(baseFee))
+	}
+	return new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gasUsed))
+}
+
+/**
+wait for the outcome of the relay txs a stopped relayer left pending, settling them in the store and the nonce manager.
+The txs that timed out are checked again, they may have been included since. A tx still without an outcome
+when ctx is done stays as it is
+*/
+func (executor *COREExecutor) ResumePendingRelays(ctx context.Context) error {
+	if executor.db == nil {
+		return nil
+	}
+	var attempts []model.RelayAttempt
+	outcomes := []string{model.OutcomePending, model.OutcomeTimedOut}
+	if err := executor.db.Where("outcome IN (?)", outcomes).Order("nonce").Find(&attempts).Error; err != nil {
+		return err
+	}
+	if len(attempts) > 0 {
+		relayercommon.Logger.Infof("resume %d pending or timed out relay txs", len(attempts))
+	}
+
+	var wg sync.WaitGroup
+	for i := range attempts {
+		wg.Add(1)
+		go func(attempt *model.RelayAttempt) {
+			defer wg.Done()
+			executor.resumeRelay(ctx, attempt)
+		}(&attempts[i])
+	}
+	wg.Wait()
+	return nil
+}
+
+/**
+wait for the outcome of the relay tx of attempt and its recorded replacements. A replaced tx leaves the mempool,
+the ones the provider does not know are skipped unless none is left
+*/
+func (executor *COREExecutor) resumeRelay(ctx context.Context, attempt *model.RelayAttempt) {
+	blockHash, err := chainhash.NewHashFromStr(attempt.BlockHash)
+	if err != nil {
+		relayercommon.Logger.Errorf("invalid block hash of relay attempt %d, err=%s", attempt.ID, err.Error())
+		return
+	}
+	var txHashes []common.Hash
+	var txs []*types.Transaction
+	for _, hash := range attempt.SentTxHashes() {
+		txHash := common.HexToHash(hash)
+		txHashes = append(txHashes, txHash)
+		tx, _, err := executor.GetClient().TransactionByHash(ctx, txHash)
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			relayercommon.Logger.Warningf("get %s relay tx %s error, leave it as it is, err=%s", attempt.Outcome, hash, err.Error())
+			return
+		}
+		txs = append(txs, tx)
+	}
+	if len(txs) == 0 {
+		//gone from the mempool, or replaced by a tx the stopped relayer did not record
+		result := &RelayResult{Outcome: TxDropped, TxHashes: txHashes, Err: ethereum.NotFound}
+		if len(txHashes) > 0 {
+			result.TxHash = txHashes[len(txHashes)-1]
+		}
+		executor.settleAttempt(attempt.ID, nil, result)
+		return
+	}
+
+	latest := txs[len(txs)-1]
+	result := executor.waitRelay(ctx, blockHash, attempt.ID, txs)
+	if ctx.Err() != nil {
+		return
+	}
+	executor.settleNonce(latest.Nonce(), result)
+	relayercommon.Logger.Infof("resumed relay outcome:%s, blockHash:%s, txHash:%s", result.Outcome, attempt.BlockHash, result.TxHash.String())
+	executor.settleAttempt(attempt.ID, latest, result)
+}
@@ -0,0 +1,225 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/coredao-org/btc-relayer/config"
+	"github.com/coredao-org/btc-relayer/model"
+)
+
+func newTestStore(t *testing.T) *gorm.DB {
+	db, err := model.OpenDB(&config.DBConfig{DBPath: filepath.Join(t.TempDir(), "relay.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func relayAttempts(t *testing.T, db *gorm.DB) []model.RelayAttempt {
+	var attempts []model.RelayAttempt
+	require.NoError(t, db.Order("id").Find(&attempts).Error)
+	return attempts
+}
+
+func TestRelayFee(t *testing.T) {
+	legacy := types.NewTx(&types.LegacyTx{GasPrice: big.NewInt(2000000000)})
+	require.Equal(t, big.NewInt(100000000000000), relayFee(legacy, 50000, nil))
+	require.Equal(t, big.NewInt(100000000000000), relayFee(legacy, 50000, big.NewInt(1500000000)))
+
+	dynamic := types.NewTx(&types.DynamicFeeTx{GasTipCap: big.NewInt(3), GasFeeCap: big.NewInt(10)})
+	require.Equal(t, big.NewInt(8*50000), relayFee(dynamic, 50000, big.NewInt(5)))
+	//the tip is cut to the fee cap
+	require.Equal(t, big.NewInt(10*50000), relayFee(dynamic, 50000, big.NewInt(9)))
+}
+
+func TestCOREExecutor_RecordRelayAttempts(t *testing.T) {
+	eth, server := newFakeCoreNode(t)
+	defer server.Close()
+	eth.nonce = 5
+	eth.setHead(100)
+	eth.call = lightClientCall(t, false, common.Address{})
+	eth.onSend = func(tx *types.Transaction) {
+		eth.setTx(tx, &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(100), GasUsed: 60000})
+	}
+	executor := newTestCOREExecutor(t, server.URL, config.COREConfig{GasLimit: 4700000})
+	executor.db = newTestStore(t)
+
+	task := newTestTask(t, 200)
+	submission, err := executor.SubmitBTCLightMirror(context.Background(), task)
+	require.NoError(t, err)
+	attempts := relayAttempts(t, executor.db)
+	require.Len(t, attempts, 1)
+	require.Equal(t, model.OutcomePending, attempts[0].Outcome)
+
+	txHash, err := executor.WaitBTCLightMirror(context.Background(), submission)
+	require.NoError(t, err)
+
+	//the simulation of the next header reverts
+	eth.estimateGas = func(args map[string]interface{}) (hexutil.Uint64, error) {
+		return 0, &revertError{reason: "can not find previous block"}
+	}
+	_, err = executor.SubmitBTCLightMirror(context.Background(), newTestTask(t, 202))
+	require.Error(t, err)
+
+	attempts = relayAttempts(t, executor.db)
+	require.Len(t, attempts, 2)
+	included := attempts[0]
+	require.Equal(t, int64(200), included.Height)
+	require.Equal(t, task.BlockHash.String(), included.BlockHash)
+	require.Equal(t, task.BLOCK.Header.Timestamp.Unix(), included.BlockTime.Unix())
+	require.Greater(t, included.MirrorSize, 80)
+	require.Equal(t, txHash.String(), included.TxHash)
+	require.Equal(t, uint64(5), included.Nonce)
+	require.Equal(t, TxIncluded.String(), included.Outcome)
+	require.True(t, included.Won)
+	require.Equal(t, executor.txSender.String(), included.Submitter)
+	require.Equal(t, uint64(60000), included.GasUsed)
+	require.Equal(t, new(big.Int).Mul(big.NewInt(DefaultGasPrice), big.NewInt(60000)).String(), included.FeePaid)
+	require.NotNil(t, included.IncludedAt)
+	require.Equal(t, int64(testCoreGenesisTime+3*100), included.IncludedAt.Unix())
+
+	failed := attempts[1]
+	require.Equal(t, int64(202), failed.Height)
+	require.Equal(t, model.OutcomeSimulationFailed, failed.Outcome)
+	require.Contains(t, failed.Error, "can not find previous block")
+	require.Empty(t, failed.TxHash)
+	require.False(t, failed.Won)
+}
+
+func TestCOREExecutor_ResumePendingRelays(t *testing.T) {
+	eth, server := newFakeCoreNode(t)
+	defer server.Close()
+	eth.setHead(100)
+	eth.call = lightClientCall(t, true, common.HexToAddress("0x01"))
+	executor := newTestCOREExecutor(t, server.URL, config.COREConfig{GasLimit: 4700000})
+	executor.db = newTestStore(t)
+
+	//left pending by the last run, one is included and one is unknown to the node
+	tx := signedTestTx(t)
+	eth.setTx(tx, &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(99), GasUsed: 50000})
+	task := newTestTask(t, 300)
+	executor.recordSentAttempt(task, []byte{0x01}, tx)
+	executor.recordSentAttempt(newTestTask(t, 301), []byte{0x01}, types.NewTx(&types.LegacyTx{Nonce: 8, Gas: 21000, GasPrice: big.NewInt(1)}))
+
+	require.NoError(t, executor.ResumePendingRelays(context.Background()))
+	attempts := relayAttempts(t, executor.db)
+	require.Len(t, attempts, 2)
+	require.Equal(t, TxIncluded.String(), attempts[0].Outcome)
+	require.Equal(t, tx.Hash().String(), attempts[0].TxHash)
+	require.Equal(t, uint64(50000), attempts[0].GasUsed)
+	require.NotEmpty(t, attempts[0].FeePaid)
+	require.Equal(t, TxDropped.String(), attempts[1].Outcome)
+
+	//nothing is pending any more
+	require.NoError(t, executor.ResumePendingRelays(context.Background()))
+}
+
+func TestCOREExecutor_ResumeReplacedRelay(t *testing.T) {
+	eth, server := newFakeCoreNode(t)
+	defer server.Close()
+	eth.setHead(100)
+	eth.call = lightClientCall(t, false, common.Address{})
+	executor := newTestCOREExecutor(t, server.URL, config.COREConfig{GasLimit: 4700000, StuckTxBlocks: 2, MaxGasPrice: 100000000000})
+	executor.db = newTestStore(t)
+
+	tx := signedTestTx(t)
+	eth.setTx(tx, nil)
+	eth.nonce = tx.Nonce()
+	attemptID := executor.recordSentAttempt(newTestTask(t, 300), []byte{0x01}, tx)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		eth.setHead(102)
+	}()
+
+	//stopped after the stuck tx is replaced, the replacement is recorded against the attempt
+	ctx, cancel := context.WithTimeout(context.Background(), 800*time.Millisecond)
+	defer cancel()
+	result := executor.waitRelay(ctx, testBtcHash(t), attemptID, []*types.Transaction{tx})
+	require.Error(t, ctx.Err())
+	require.Len(t, eth.sent, 1)
+	replacement := eth.sent[0]
+	require.Equal(t, []common.Hash{tx.Hash(), replacement.Hash()}, result.TxHashes)
+	attempts := relayAttempts(t, executor.db)
+	require.Equal(t, model.OutcomePending, attempts[0].Outcome)
+	require.Equal(t, []string{tx.Hash().String(), replacement.Hash().String()}, attempts[0].SentTxHashes())
+
+	//the replacement is included while the relayer is stopped, the replaced tx left the mempool
+	eth.forget(tx.Hash())
+	eth.setTx(replacement, &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(102), GasUsed: 50000})
+	require.NoError(t, executor.ResumePendingRelays(context.Background()))
+	attempts = relayAttempts(t, executor.db)
+	require.Equal(t, TxIncluded.String(), attempts[0].Outcome)
+	require.Equal(t, replacement.Hash().String(), attempts[0].TxHash)
+	require.Len(t, attempts[0].SentTxHashes(), 2)
+}
+
+func TestCOREExecutor_ResumeTimedOutRelays(t *testing.T) {
+	eth, server := newFakeCoreNode(t)
+	defer server.Close()
+	eth.setHead(100)
+	eth.call = lightClientCall(t, false, common.Address{})
+	executor := newTestCOREExecutor(t, server.URL, config.COREConfig{GasLimit: 4700000})
+	executor.db = newTestStore(t)
+
+	//both timed out, one is included since and one is unknown to the node
+	tx := signedTestTx(t)
+	includedID := executor.recordSentAttempt(newTestTask(t, 300), []byte{0x01}, tx)
+	executor.settleAttempt(includedID, tx, &RelayResult{Outcome: TxTimedOut, TxHash: tx.Hash()})
+	unknown := types.NewTx(&types.LegacyTx{Nonce: 8, Gas: 21000, GasPrice: big.NewInt(1)})
+	unknownID := executor.recordSentAttempt(newTestTask(t, 301), []byte{0x01}, unknown)
+	executor.settleAttempt(unknownID, unknown, &RelayResult{Outcome: TxTimedOut, TxHash: unknown.Hash()})
+	require.Equal(t, model.OutcomeTimedOut, relayAttempts(t, executor.db)[0].Outcome)
+
+	eth.setTx(tx, &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(99), GasUsed: 50000})
+	require.NoError(t, executor.ResumePendingRelays(context.Background()))
+	attempts := relayAttempts(t, executor.db)
+	require.Equal(t, TxIncluded.String(), attempts[0].Outcome)
+	require.True(t, attempts[0].Won)
+	require.Equal(t, uint64(50000), attempts[0].GasUsed)
+	require.Equal(t, TxDropped.String(), attempts[1].Outcome)
+}
+
+func TestCOREExecutor_WaitStopped(t *testing.T) {
+	eth, server := newFakeCoreNode(t)
+	defer server.Close()
+	eth.setHead(100)
+	eth.call = lightClientCall(t, false, common.Address{})
+	executor := newTestCOREExecutor(t, server.URL, config.COREConfig{GasLimit: 4700000})
+	executor.db = newTestStore(t)
+
+	submission, err := executor.SubmitBTCLightMirror(context.Background(), newTestTask(t, 200))
+	require.NoError(t, err)
+
+	//shut down before the tx is included, it is left pending to be resumed
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = executor.WaitBTCLightMirror(ctx, submission)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	attempts := relayAttempts(t, executor.db)
+	require.Len(t, attempts, 1)
+	require.Equal(t, model.OutcomePending, attempts[0].Outcome)
+}
+
+func TestCOREExecutor_NoStore(t *testing.T) {
+	executor := &COREExecutor{}
+	executor.recordFailedAttempt(newTestTask(t, 1), nil, 0, model.OutcomeSendFailed, ErrRelayFailed)
+	require.Zero(t, executor.recordSentAttempt(newTestTask(t, 1), nil, newTestTx()))
+	require.NoError(t, executor.ResumePendingRelays(context.Background()))
+}
+
+func TestOutcomeSuperseded(t *testing.T) {
+	//the history summary counts headers won by competitors by this outcome
+	require.Equal(t, model.OutcomeSuperseded, TxSuperseded.String())
+	//the timed out rows are resumed by this outcome
+	require.Equal(t, model.OutcomeTimedOut, TxTimedOut.String())
+}
@@ -0,0 +1,153 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+// Signer signs the transactions sent by the relayer account
+type Signer interface {
+	Address() common.Address
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+/**
+create the signer configured in core_config, an external signer if external_signer is set,
+otherwise a local signer holding the key selected by key_type
+*/
+func NewSigner(cfg *config.COREConfig) (Signer, error) {
+	if cfg.ExternalSigner != "" {
+		return NewExternalSigner(cfg.ExternalSigner, common.HexToAddress(cfg.SignerAddress))
+	}
+
+	privKey, err := getPrivateKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewLocalSigner(privKey), nil
+}
+
+// LocalSigner signs with a private key held in memory
+type LocalSigner struct {
+	privateKey *ecdsa.PrivateKey
+	address    common.Address
+}
+
+func NewLocalSigner(privateKey *ecdsa.PrivateKey) *LocalSigner {
+	return &LocalSigner{
+		privateKey: privateKey,
+		address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+	}
+}
+
+func (signer *LocalSigner) Address() common.Address {
+	return signer.address
+}
+
+func (signer *LocalSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), signer.privateKey)
+}
+
+// ExternalSigner asks a remote signer speaking the Clef account_signTransaction json-rpc to sign,
+// so the relayer never holds the key
+type ExternalSigner struct {
+	client   *rpc.Client
+	endpoint string
+	address  common.Address
+}
+
+// signTransactionResult is the result of account_signTransaction
+type signTransactionResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+func NewExternalSigner(endpoint string, address common.Address) (*ExternalSigner, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("dial external signer %s error: %s", endpoint, err.Error())
+	}
+	return &ExternalSigner{
+		client:   client,
+		endpoint: endpoint,
+		address:  address,
+	}, nil
+}
+
+func (signer *ExternalSigner) Address() common.Address {
+	return signer.address
+}
+
+func (signer *ExternalSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	data := hexutil.Bytes(tx.Data())
+	args := &apitypes.SendTxArgs{
+		From:    common.NewMixedcaseAddress(signer.address),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   hexutil.Big(*tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    &data,
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if tx.To() != nil {
+		to := common.NewMixedcaseAddress(*tx.To())
+		args.To = &to
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	} else {
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	}
+
+	var result signTransactionResult
+	if err := signer.client.CallContext(ctx, &result, "account_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("external signer %s error: %s", signer.endpoint, err.Error())
+	}
+
+	signedTx := new(types.Transaction)
+	if err := signedTx.UnmarshalBinary(result.Raw); err != nil {
+		return nil, fmt.Errorf("decode signed tx error: %s", err.Error())
+	}
+
+	// make sure the signer signed what we asked for, by the expected account
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signedTx)
+	if err != nil {
+		return nil, err
+	}
+	if sender != signer.address {
+		return nil, fmt.Errorf("external signer signed with %s, expected %s", sender.String(), signer.address.String())
+	}
+	if !sameUnsignedTx(signedTx, tx, chainID) {
+		return nil, fmt.Errorf("external signer returned a different transaction")
+	}
+	return signedTx, nil
+}
+
+/**
+whether signed is tx for chainID, with the same type, fees, nonce, gas, recipient, value and data
+*/
+func sameUnsignedTx(signed *types.Transaction, tx *types.Transaction, chainID *big.Int) bool {
+	if signed.Type() != tx.Type() || signed.ChainId().Cmp(chainID) != 0 ||
+		signed.GasPrice().Cmp(tx.GasPrice()) != 0 || signed.GasFeeCap().Cmp(tx.GasFeeCap()) != 0 ||
+		signed.GasTipCap().Cmp(tx.GasTipCap()) != 0 {
+		return false
+	}
+	if signed.Nonce() != tx.Nonce() || signed.Gas() != tx.Gas() || signed.Value().Cmp(tx.Value()) != 0 ||
+		!bytes.Equal(signed.Data(), tx.Data()) || (signed.To() == nil) != (tx.To() == nil) ||
+		(tx.To() != nil && *signed.To() != *tx.To()) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package executor
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+// fakeClef implements account_signTransaction with a local key, like Clef does after approval
+type fakeClef struct {
+	key      *ecdsa.PrivateKey
+	requests []apitypes.SendTxArgs
+	// tamper changes the request before signing, like a misconfigured or compromised signer
+	tamper func(args *apitypes.SendTxArgs)
+}
+
+func (clef *fakeClef) SignTransaction(ctx context.Context, args apitypes.SendTxArgs, methodSelector *string) (*signTransactionResult, error) {
+	clef.requests = append(clef.requests, args)
+	if clef.tamper != nil {
+		clef.tamper(&args)
+	}
+	tx := args.ToTransaction()
+	signed, err := types.SignTx(tx, types.LatestSignerForChainID((*big.Int)(args.ChainID)), clef.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &signTransactionResult{Raw: raw, Tx: signed}, nil
+}
+
+func newFakeClef(t *testing.T, key *ecdsa.PrivateKey) (*fakeClef, *httptest.Server) {
+	clef := &fakeClef{key: key}
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("account", clef))
+	return clef, httptest.NewServer(server)
+}
+
+func newTestTx() *types.Transaction {
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    7,
+		To:       &pcsAddr,
+		Value:    big.NewInt(0),
+		Gas:      4700000,
+		GasPrice: big.NewInt(DefaultGasPrice),
+		Data:     []byte{0x01, 0x02, 0x03},
+	})
+}
+
+func TestLocalSigner(t *testing.T) {
+	privKey, err := crypto.HexToECDSA(testPrivateKey)
+	require.NoError(t, err)
+
+	signer := NewLocalSigner(privKey)
+	require.Equal(t, testAddress, signer.Address().String())
+
+	chainID := big.NewInt(1116)
+	signed, err := signer.SignTx(context.Background(), newTestTx(), chainID)
+	require.NoError(t, err)
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	require.NoError(t, err)
+	require.Equal(t, signer.Address(), sender)
+}
+
+func TestExternalSigner(t *testing.T) {
+	privKey, err := crypto.HexToECDSA(testPrivateKey)
+	require.NoError(t, err)
+	clef, server := newFakeClef(t, privKey)
+	defer server.Close()
+
+	signer, err := NewSigner(&config.COREConfig{ExternalSigner: server.URL, SignerAddress: testAddress})
+	require.NoError(t, err)
+	require.Equal(t, testAddress, signer.Address().String())
+
+	chainID := big.NewInt(1116)
+	tx := newTestTx()
+	signed, err := signer.SignTx(context.Background(), tx, chainID)
+	require.NoError(t, err)
+	require.Equal(t, tx.Nonce(), signed.Nonce())
+	require.Equal(t, tx.Data(), signed.Data())
+
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	require.NoError(t, err)
+	require.Equal(t, testAddress, sender.String())
+
+	require.Len(t, clef.requests, 1)
+	require.Equal(t, testAddress, clef.requests[0].From.Address().String())
+	require.Equal(t, (*hexutil.Big)(chainID), clef.requests[0].ChainID)
+}
+
+func TestExternalSigner_WrongAccount(t *testing.T) {
+	otherKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	_, server := newFakeClef(t, otherKey)
+	defer server.Close()
+
+	signer, err := NewExternalSigner(server.URL, common.HexToAddress(testAddress))
+	require.NoError(t, err)
+
+	_, err = signer.SignTx(context.Background(), newTestTx(), big.NewInt(1116))
+	require.Error(t, err)
+}
+
+func TestExternalSigner_Tampered(t *testing.T) {
+	privKey, err := crypto.HexToECDSA(testPrivateKey)
+	require.NoError(t, err)
+	clef, server := newFakeClef(t, privKey)
+	defer server.Close()
+
+	signer, err := NewExternalSigner(server.URL, common.HexToAddress(testAddress))
+	require.NoError(t, err)
+
+	dynamicTx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(1116),
+		Nonce:     7,
+		To:        &pcsAddr,
+		Value:     big.NewInt(0),
+		Gas:       4700000,
+		GasTipCap: big.NewInt(1000000000),
+		GasFeeCap: big.NewInt(DefaultGasPrice),
+		Data:      []byte{0x01, 0x02, 0x03},
+	})
+	for _, tc := range []struct {
+		name   string
+		tx     *types.Transaction
+		tamper func(args *apitypes.SendTxArgs)
+	}{
+		{"gas price", newTestTx(), func(args *apitypes.SendTxArgs) {
+			args.GasPrice = (*hexutil.Big)(big.NewInt(100 * DefaultGasPrice))
+		}},
+		{"max fee per gas", dynamicTx, func(args *apitypes.SendTxArgs) {
+			args.MaxFeePerGas = (*hexutil.Big)(big.NewInt(100 * DefaultGasPrice))
+		}},
+		{"max priority fee per gas", dynamicTx, func(args *apitypes.SendTxArgs) {
+			args.MaxPriorityFeePerGas = (*hexutil.Big)(big.NewInt(DefaultGasPrice))
+		}},
+		{"chain id", newTestTx(), func(args *apitypes.SendTxArgs) {
+			args.ChainID = (*hexutil.Big)(big.NewInt(1))
+		}},
+		{"tx type", dynamicTx, func(args *apitypes.SendTxArgs) {
+			args.GasPrice = args.MaxFeePerGas
+			args.MaxFeePerGas = nil
+			args.MaxPriorityFeePerGas = nil
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clef.tamper = nil
+			_, err := signer.SignTx(context.Background(), tc.tx, big.NewInt(1116))
+			require.NoError(t, err)
+
+			clef.tamper = tc.tamper
+			_, err = signer.SignTx(context.Background(), tc.tx, big.NewInt(1116))
+			require.Error(t, err)
+		})
+	}
+}
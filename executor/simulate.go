@@ -0,0 +1,43 @@
+package executor
+
+import (
+	"context"
+)
+
+// RelaySimulation is the relay tx of a payload simulated with eth_call, nothing is signed or sent
+type RelaySimulation struct {
+	Payload *RelayPayload
+	// GasLimit is what the relay tx would be sent with, the estimated gas with gas_margin_percent
+	GasLimit uint64
+	// ReturnCode is the predicted return code of storeBlockHeader, 0 if the header would be stored
+	ReturnCode int64
+	// Err is a RelayError if storeBlockHeader would revert or return an error code
+	Err error
+}
+
+/**
+simulate the relay tx of payload from the relayer account with eth_call and estimate its gas, without signing or sending.
+The returned error is a failure to simulate, the predicted failure of the relay tx is in the simulation
+*/
+func (executor *COREExecutor) SimulateRelayPayload(ctx context.Context, payload *RelayPayload) (*RelaySimulation, error) {
+	task := payload.Task
+	simulation := &RelaySimulation{Payload: payload}
+	returnCode, rejected, err := executor.callStoreBlockHeader(ctx, payload.data, task.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	simulation.ReturnCode = returnCode
+	if rejected != nil {
+		simulation.Err = rejected
+		//a reverted call has no gas to estimate
+		if returnCode == 0 {
+			return simulation, nil
+		}
+	}
+
+	simulation.GasLimit, err = executor.estimateRelayGas(ctx, payload.data, task.BlockHash)
+	if err != nil {
+		simulation.Err = err
+	}
+	return simulation, nil
+}
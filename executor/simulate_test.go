@@ -0,0 +1,81 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/coredao-org/btc-relayer/config"
+	cgccaller "github.com/coredao-org/btc-relayer/executor/cc"
+)
+
+// storeBlockHeaderCall answers storeBlockHeader with returnCode, or reverts with reason
+func storeBlockHeaderCall(t *testing.T, returnCode uint32, reason string) func(args map[string]interface{}, block string) (hexutil.Bytes, error) {
+	parsed, err := abi.JSON(strings.NewReader(cgccaller.CGCABI))
+	require.NoError(t, err)
+	return func(args map[string]interface{}, block string) (hexutil.Bytes, error) {
+		input, ok := args["input"].(string)
+		if !ok {
+			input, _ = args["data"].(string)
+		}
+		data, err := hexutil.Decode(input)
+		if err != nil || len(data) < 4 {
+			return nil, errors.New("invalid call data")
+		}
+		method, err := parsed.MethodById(data[:4])
+		if err != nil {
+			return nil, err
+		}
+		if method.Name != "storeBlockHeader" {
+			return nil, errors.New("unsupported method " + method.Name)
+		}
+		if reason != "" {
+			return nil, &revertError{reason: reason}
+		}
+		return method.Outputs.Pack(returnCode)
+	}
+}
+
+func TestCOREExecutor_SimulateRelayPayload(t *testing.T) {
+	eth, server := newFakeCoreNode(t)
+	defer server.Close()
+	executor := newTestCOREExecutor(t, server.URL, config.COREConfig{GasLimit: 4700000, GasMarginPercent: 20})
+	payload, err := BuildRelayPayload(newTestTask(t, 200))
+	require.NoError(t, err)
+
+	eth.call = storeBlockHeaderCall(t, 0, "")
+	simulation, err := executor.SimulateRelayPayload(context.Background(), payload)
+	require.NoError(t, err)
+	require.NoError(t, simulation.Err)
+	require.Equal(t, int64(0), simulation.ReturnCode)
+	require.Equal(t, uint64(120000), simulation.GasLimit)
+	require.Equal(t, pcsAddr.String(), payload.To())
+	require.Greater(t, payload.MirrorSize(), 80)
+
+	// an error code is predicted from the return value of eth_call
+	eth.call = storeBlockHeaderCall(t, 10030, "")
+	simulation, err = executor.SimulateRelayPayload(context.Background(), payload)
+	require.NoError(t, err)
+	require.Equal(t, int64(10030), simulation.ReturnCode)
+	require.True(t, errors.Is(simulation.Err, ErrNoPrevBlock))
+	require.True(t, errors.Is(simulation.Err, ErrSimulationReverted))
+
+	eth.call = storeBlockHeaderCall(t, 0, "block already exists")
+	simulation, err = executor.SimulateRelayPayload(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, errors.Is(simulation.Err, ErrBlockAlreadyExists))
+	require.Zero(t, simulation.GasLimit)
+
+	// the node failing is not a prediction
+	eth.call = nil
+	_, err = executor.SimulateRelayPayload(context.Background(), payload)
+	require.Error(t, err)
+
+	// nothing is signed or sent
+	require.Empty(t, eth.sent)
+}
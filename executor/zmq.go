@@ -0,0 +1,131 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/go-zeromq/zmq4"
+
+	"github.com/coredao-org/btc-relayer/common"
+)
+
+// topics of the block notifications of bitcoind, -zmqpubhashblock and -zmqpubrawblock
+const (
+	ZmqTopicHashBlock = "hashblock"
+	ZmqTopicRawBlock  = "rawblock"
+)
+
+// ZmqReconnectInterval is the time to wait before subscribing again after the subscription failed
+const ZmqReconnectInterval = 5 * time.Second
+
+/**
+subscribe to the zmq block notifications of the btc nodes with a zmq_addr, a new tip wakes up the relay loop at once.
+Polling in UpdateClients goes on as the fallback and health check. The subscriptions are tracked by wg until ctx is done
+*/
+func (executor *BTCExecutor) SubscribeBlocks(ctx context.Context, wg *sync.WaitGroup) {
+	for _, btcClient := range executor.BTCClients {
+		if btcClient.ZmqAddr == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(btcClient *BTCClient) {
+			defer wg.Done()
+			executor.subscribeBlocks(ctx, btcClient)
+		}(btcClient)
+	}
+}
+
+func (executor *BTCExecutor) subscribeBlocks(ctx context.Context, btcClient *BTCClient) {
+	for {
+		err := executor.receiveBlocks(ctx, btcClient)
+		if ctx.Err() != nil {
+			return
+		}
+		common.Logger.Warningf("zmq subscription to %s error, subscribe again in %s, err=%s", btcClient.ZmqAddr, ZmqReconnectInterval, err.Error())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ZmqReconnectInterval):
+		}
+	}
+}
+
+func (executor *BTCExecutor) receiveBlocks(ctx context.Context, btcClient *BTCClient) error {
+	sub := zmq4.NewSub(ctx, zmq4.WithAutomaticReconnect(true), zmq4.WithDialerRetry(ZmqReconnectInterval))
+	defer sub.Close()
+
+	if err := sub.Dial(btcClient.ZmqAddr); err != nil {
+		return err
+	}
+	for _, topic := range []string{ZmqTopicHashBlock, ZmqTopicRawBlock} {
+		if err := sub.SetOption(zmq4.OptionSubscribe, topic); err != nil {
+			return err
+		}
+	}
+	common.Logger.Infof("subscribed to zmq block notifications of %s", btcClient.ZmqAddr)
+
+	for {
+		msg, err := sub.Recv()
+		if err != nil {
+			return err
+		}
+		blockHash, err := blockHashOfNotification(msg.Frames)
+		if err != nil {
+			common.Logger.Warningf("invalid zmq notification from %s, err=%s", btcClient.ZmqAddr, err.Error())
+			continue
+		}
+		if err := executor.onNewBlock(btcClient, blockHash); err != nil {
+			common.Logger.Warningf("query block %s notified by %s error, err=%s", blockHash.String(), btcClient.ZmqAddr, err.Error())
+		}
+	}
+}
+
+/**
+the frames of a notification are the topic, the body and a sequence number
+*/
+func blockHashOfNotification(frames [][]byte) (*chainhash.Hash, error) {
+	if len(frames) < 2 {
+		return nil, fmt.Errorf("%d frames", len(frames))
+	}
+	topic, body := string(frames[0]), frames[1]
+	switch topic {
+	case ZmqTopicHashBlock:
+		if len(body) != chainhash.HashSize {
+			return nil, fmt.Errorf("block hash of %d bytes", len(body))
+		}
+		//the hash is published in display order
+		var blockHash chainhash.Hash
+		for i := range body {
+			blockHash[i] = body[len(body)-1-i]
+		}
+		return &blockHash, nil
+	case ZmqTopicRawBlock:
+		var header wire.BlockHeader
+		if err := header.Deserialize(bytes.NewReader(body)); err != nil {
+			return nil, err
+		}
+		blockHash := header.BlockHash()
+		return &blockHash, nil
+	}
+	return nil, fmt.Errorf("unknown topic %q", topic)
+}
+
+/**
+a notified block is the new tip of the node, update its height
+*/
+func (executor *BTCExecutor) onNewBlock(btcClient *BTCClient, blockHash *chainhash.Hash) error {
+	height, err := btcClient.BTCClient.GetBlockHeight(blockHash)
+	if err != nil {
+		return err
+	}
+	common.Logger.Infof("new block notified by %s, height:%d, hash:%s", btcClient.ZmqAddr, height, blockHash.String())
+	executor.setClientHeight(btcClient, height)
+	executor.updateHighestHeight()
+	return nil
+}
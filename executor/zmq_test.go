@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/go-zeromq/zmq4"
+	"github.com/stretchr/testify/require"
+)
+
+// zmqPublisher stands in for the zmq publisher of bitcoind
+type zmqPublisher struct {
+	pub      zmq4.Socket
+	sequence uint32
+}
+
+func newZmqPublisher(t *testing.T) *zmqPublisher {
+	pub := zmq4.NewPub(context.Background())
+	require.NoError(t, pub.Listen("tcp://127.0.0.1:0"))
+	return &zmqPublisher{pub: pub}
+}
+
+func (publisher *zmqPublisher) addr() string {
+	return "tcp://" + publisher.pub.Addr().String()
+}
+
+func (publisher *zmqPublisher) publish(t *testing.T, topic string, body []byte) {
+	sequence := make([]byte, 4)
+	binary.LittleEndian.PutUint32(sequence, publisher.sequence)
+	publisher.sequence++
+	require.NoError(t, publisher.pub.Send(zmq4.NewMsgFrom([]byte(topic), body, sequence)))
+}
+
+func hashBlockBody(blockHash chainhash.Hash) []byte {
+	body := make([]byte, chainhash.HashSize)
+	for i := range body {
+		body[i] = blockHash[chainhash.HashSize-1-i]
+	}
+	return body
+}
+
+func rawBlockBody(t *testing.T, block *wire.MsgBlock) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, block.Serialize(&buf))
+	return buf.Bytes()
+}
+
+/**
+publish until the executor sees the new tip, the subscription is registered with the publisher asynchronously
+*/
+func publishUntilNewTip(t *testing.T, publisher *zmqPublisher, executor *BTCExecutor, topic string, body []byte) {
+	for i := 0; i < 50; i++ {
+		publisher.publish(t, topic, body)
+		if executor.WaitNewTip(context.Background(), 100 * time.Millisecond) {
+			return
+		}
+	}
+	t.Fatalf("no new tip after %s notifications", topic)
+}
+
+func TestSubscribeBlocks(t *testing.T) {
+	bitcoind, server := newFakeBitcoind(t)
+	defer server.Close()
+	publisher := newZmqPublisher(t)
+	defer publisher.pub.Close()
+
+	executor := newTestBTCExecutor(t, server.URL)
+	executor.BTCClients[0].ZmqAddr = publisher.addr()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	executor.SubscribeBlocks(ctx, &wg)
+
+	block := newTestBlock(t)
+	bitcoind.addBlock(block, 101)
+	publishUntilNewTip(t, publisher, executor, ZmqTopicHashBlock, hashBlockBody(block.BlockHash()))
+	require.Equal(t, int64(101), executor.HighestHeight)
+
+	next := newTestBlock(t)
+	next.Header.PrevBlock = block.BlockHash()
+	bitcoind.addBlock(next, 102)
+	publishUntilNewTip(t, publisher, executor, ZmqTopicRawBlock, rawBlockBody(t, next))
+	require.Equal(t, int64(102), executor.HighestHeight)
+	require.Equal(t, int64(102), executor.BTCClients[0].CurrentHeight)
+
+	// an old block does not lower the height
+	publisher.publish(t, ZmqTopicHashBlock, hashBlockBody(block.BlockHash()))
+	require.False(t, executor.WaitNewTip(context.Background(), 200*time.Millisecond))
+	require.Equal(t, int64(102), executor.HighestHeight)
+
+	// the subscription returns once ctx is done
+	cancel()
+	wg.Wait()
+}
+
+func TestBlockHashOfNotification(t *testing.T) {
+	block := newTestBlock(t)
+	blockHash := block.BlockHash()
+
+	hash, err := blockHashOfNotification([][]byte{[]byte(ZmqTopicHashBlock), hashBlockBody(blockHash), {0, 0, 0, 0}})
+	require.NoError(t, err)
+	require.Equal(t, blockHash, *hash)
+	hash, err = blockHashOfNotification([][]byte{[]byte(ZmqTopicRawBlock), rawBlockBody(t, block), {1, 0, 0, 0}})
+	require.NoError(t, err)
+	require.Equal(t, blockHash, *hash)
+
+	_, err = blockHashOfNotification([][]byte{[]byte(ZmqTopicHashBlock), {1, 2, 3}})
+	require.Error(t, err)
+	_, err = blockHashOfNotification([][]byte{[]byte(ZmqTopicRawBlock), {1, 2, 3}})
+	require.Error(t, err)
+	_, err = blockHashOfNotification([][]byte{[]byte("sequence"), {1, 2, 3}})
+	require.Error(t, err)
+	_, err = blockHashOfNotification([][]byte{[]byte(ZmqTopicHashBlock)})
+	require.Error(t, err)
+}
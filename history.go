@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/coredao-org/btc-relayer/model"
+)
+
+const (
+	historyFormatTable = "table"
+	historyFormatJSON  = "json"
+	historyFormatCSV   = "csv"
+)
+
+var historyColumns = []string{"created_at", "height", "block_hash", "outcome", "won", "submitter", "tx_hash", "nonce", "mirror_size", "gas_used", "fee_paid", "latency", "error"}
+
+// parse a --since or --until flag, a date is midnight UTC
+func parseHistoryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func historyCmd(args []string) int {
+	fs, configPath := newFlagSet("history")
+	filter := &model.HistoryFilter{}
+	fs.Int64Var(&filter.FromHeight, "from-height", 0, "first btc height")
+	fs.Int64Var(&filter.ToHeight, "to-height", 0, "last btc height")
+	since := fs.String("since", "", "attempts made at or after this time, 2006-01-02 or RFC3339")
+	until := fs.String("until", "", "attempts made before this time, 2006-01-02 or RFC3339")
+	fs.StringVar(&filter.Outcome, "outcome", "", "only attempts with this outcome, e.g. included, superseded, reverted, dropped, \"timed out\", pending, \"simulation failed\", \"send failed\"")
+	fs.StringVar(&filter.TxHash, "tx-hash", "", "only the attempt of this Core tx")
+	format := fs.String("format", historyFormatTable, "output format: table, json or csv")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	var err error
+	if filter.Since, err = parseHistoryTime(*since); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --since: %s\n", err.Error())
+		return exitCodeUsage
+	}
+	if filter.Until, err = parseHistoryTime(*until); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --until: %s\n", err.Error())
+		return exitCodeUsage
+	}
+	if *format != historyFormatTable && *format != historyFormatJSON && *format != historyFormatCSV {
+		fmt.Fprintf(os.Stderr, "invalid --format %q, should be table, json or csv\n", *format)
+		return exitCodeUsage
+	}
+
+	cfg, err := initStoreCfg(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to get configuration: %s\n", err.Error())
+		return exitCodeConfigError
+	}
+	db, err := model.OpenDB(&cfg.DBConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitCodeError
+	}
+	defer db.Close()
+
+	attempts, err := model.QueryAttempts(db, filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "query relay history error: %s\n", err.Error())
+		return exitCodeError
+	}
+	summary := model.Summarize(attempts)
+
+	switch *format {
+	case historyFormatJSON:
+		err = writeHistoryJSON(os.Stdout, attempts, summary)
+	case historyFormatCSV:
+		//the summary goes to stderr so the csv can be redirected to a file as is
+		err = writeHistoryCSV(os.Stdout, attempts)
+		writeHistorySummary(os.Stderr, summary)
+	default:
+		err = writeHistoryTable(os.Stdout, attempts)
+		fmt.Println()
+		writeHistorySummary(os.Stdout, summary)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitCodeError
+	}
+	return exitCodeOK
+}
+
+// the values of historyColumns of an attempt
+func historyRow(attempt *model.RelayAttempt) []string {
+	latency := ""
+	if attempt.IncludedAt != nil {
+		latency = attempt.IncludedAt.Sub(attempt.BlockTime).String()
+	}
+	return []string{
+		attempt.CreatedAt.UTC().Format(time.RFC3339),
+		strconv.FormatInt(attempt.Height, 10),
+		attempt.BlockHash,
+		attempt.Outcome,
+		strconv.FormatBool(attempt.Won),
+		attempt.Submitter,
+		attempt.TxHash,
+		strconv.FormatUint(attempt.Nonce, 10),
+		strconv.Itoa(attempt.MirrorSize),
+		strconv.FormatUint(attempt.GasUsed, 10),
+		attempt.FeePaid,
+		latency,
+		attempt.Error,
+	}
+}
+
+func writeHistoryTable(w io.Writer, attempts []model.RelayAttempt) error {
+	//the error is left for the json and csv output, it is too long for a table
+	columns := historyColumns[:len(historyColumns)-1]
+	rows := [][]string{columns}
+	for i := range attempts {
+		rows = append(rows, historyRow(&attempts[i])[:len(columns)])
+	}
+	return writeTable(w, rows)
+}
+
+func writeHistoryCSV(w io.Writer, attempts []model.RelayAttempt) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(historyColumns); err != nil {
+		return err
+	}
+	for i := range attempts {
+		if err := cw.Write(historyRow(&attempts[i])); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeHistoryJSON(w io.Writer, attempts []model.RelayAttempt, summary *model.HistorySummary) error {
+	if attempts == nil {
+		attempts = []model.RelayAttempt{}
+	}
+	report := struct {
+		Attempts []model.RelayAttempt `json:"attempts"`
+		Summary  interface{}          `json:"summary"`
+	}{
+		Attempts: attempts,
+		Summary: struct {
+			Attempts             int     `json:"attempts"`
+			RelayedByUs          int     `json:"relayed_by_us"`
+			RelayedByCompetitors int     `json:"relayed_by_competitors"`
+			TotalFee             string  `json:"total_fee"`
+			AvgLatencySeconds    float64 `json:"avg_latency_seconds"`
+		}{
+			Attempts:             summary.Attempts,
+			RelayedByUs:          summary.RelayedByUs,
+			RelayedByCompetitors: summary.RelayedByCompetitors,
+			TotalFee:             summary.TotalFee.String(),
+			AvgLatencySeconds:    summary.AvgLatency.Seconds(),
+		},
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func writeHistorySummary(w io.Writer, summary *model.HistorySummary) {
+	fmt.Fprintf(w, "attempts:               %d\n", summary.Attempts)
+	fmt.Fprintf(w, "relayed by us:          %d\n", summary.RelayedByUs)
+	fmt.Fprintf(w, "relayed by competitors: %d\n", summary.RelayedByCompetitors)
+	fmt.Fprintf(w, "total fee:              %s wei (%s CORE)\n", summary.TotalFee.String(), weiToCore(summary.TotalFee))
+	fmt.Fprintf(w, "avg latency:            %s\n", summary.AvgLatency.Round(time.Second))
+}
+
+func weiToCore(wei *big.Int) string {
+	return new(big.Rat).SetFrac(wei, big.NewInt(1e18)).FloatString(6)
+}
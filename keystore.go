@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	config "github.com/coredao-org/btc-relayer/config"
+	"github.com/coredao-org/btc-relayer/executor"
+)
+
+func printKeystoreUsage() {
+	fmt.Fprint(os.Stderr, "usage: ./btc-relayer keystore <new|import> [flags]\n\n")
+	fmt.Fprint(os.Stderr, "  new      generate a new relayer key into an encrypted keystore file\n")
+	fmt.Fprint(os.Stderr, "  import   encrypt an existing hex private key into a keystore file\n")
+}
+
+func newKeystoreFlagSet(name string) (*flag.FlagSet, *string, *string) {
+	fs := flag.NewFlagSet("keystore "+name, flag.ContinueOnError)
+	keystoreDir := fs.String("keystore-dir", "keystore", "directory to write the keystore file to")
+	passwordFile := fs.String("password-file", "", fmt.Sprintf("file holding the keystore password (default: env %s)", config.DefaultKeystorePasswordEnv))
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: ./btc-relayer keystore %s [flags]\n", name)
+		fs.PrintDefaults()
+	}
+	return fs, keystoreDir, passwordFile
+}
+
+func keystoreCmd(args []string) int {
+	if len(args) == 0 {
+		printKeystoreUsage()
+		return exitCodeUsage
+	}
+
+	var fs *flag.FlagSet
+	var keystoreDir, passwordFile, privateKeyFile *string
+	switch args[0] {
+	case "new":
+		fs, keystoreDir, passwordFile = newKeystoreFlagSet("new")
+	case "import":
+		fs, keystoreDir, passwordFile = newKeystoreFlagSet("import")
+		privateKeyFile = fs.String("private-key-file", "", "file holding the hex private key to import")
+	default:
+		fmt.Fprintf(os.Stderr, "unknown keystore command: %s\n\n", args[0])
+		printKeystoreUsage()
+		return exitCodeUsage
+	}
+	if ok, code := parseFlags(fs, args[1:]); !ok {
+		return code
+	}
+
+	password, err := executor.GetKeystorePassword(*passwordFile, config.DefaultKeystorePasswordEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitCodeUsage
+	}
+
+	var privKey *ecdsa.PrivateKey
+	if privateKeyFile != nil {
+		if *privateKeyFile == "" {
+			fmt.Fprintln(os.Stderr, "--private-key-file is required")
+			fs.Usage()
+			return exitCodeUsage
+		}
+		bz, err := ioutil.ReadFile(*privateKeyFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return exitCodeError
+		}
+		privKey, err = crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(string(bz)), "0x"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid private key: %s\n", err.Error())
+			return exitCodeError
+		}
+	} else {
+		privKey, err = crypto.GenerateKey()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return exitCodeError
+		}
+	}
+
+	ks := keystore.NewKeyStore(*keystoreDir, keystore.StandardScryptN, keystore.StandardScryptP)
+	account, err := ks.ImportECDSA(privKey, password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "write keystore error: %s\n", err.Error())
+		return exitCodeError
+	}
+
+	fmt.Printf("address:  %s\n", account.Address.String())
+	fmt.Printf("keystore: %s\n", account.URL.Path)
+	fmt.Println("set core_config.key_type to local_keystore and core_config.keystore_path to the keystore file to use it")
+	return exitCodeOK
+}
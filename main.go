@@ -1,62 +1,81 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"github.com/coredao-org/btc-relayer/common"
-	config "github.com/coredao-org/btc-relayer/config"
-	"github.com/coredao-org/btc-relayer/executor"
-	"github.com/coredao-org/btc-relayer/relayer"
+	"os"
+	"strings"
 )
 
 const (
-	flagConfigPath = "config.json"
+	flagConfigPath = "config-path"
+
+	exitCodeOK          = 0
+	exitCodeError       = 1
+	exitCodeUsage       = 2
+	exitCodeConfigError = 3
 )
 
-func printUsage() {
-	fmt.Print("usage: ./btc-relayer --config-path configFile\n")
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) int
 }
 
-/**
-init cfg
-*/
-func initCfg() *config.Config {
-
-	var cfg *config.Config
-
-	cfg = config.ParseConfigFromFile(flagConfigPath)
+var commands = []*command{
+	{name: "run", usage: "start the relayer daemon", run: runCmd},
+	{name: "status", usage: "print relayer account and light client status", run: statusCmd},
+	{name: "register", usage: "register the relayer account to RelayerHub", run: registerCmd},
+	{name: "unregister", usage: "unregister the relayer account from RelayerHub", run: unregisterCmd},
+	{name: "relay-range", usage: "relay btc blocks in the height range [--from, --to]", run: relayRangeCmd},
+	{name: "relay-hash", usage: "relay the btc block of a hash, relay-hash <blockhash>", run: relayHashCmd},
+	{name: "history", usage: "list recorded relay attempts with fee and latency aggregates", run: historyCmd},
+	{name: "check-config", usage: "validate the config file without connecting to any node", run: checkConfigCmd},
+	{name: "keystore", usage: "generate or import the relayer key into an encrypted keystore file", run: keystoreCmd},
+}
 
-	if cfg == nil {
-		common.Logger.Infof("failed to get configuration")
+func printUsage() {
+	fmt.Fprint(os.Stderr, "usage: ./btc-relayer [command] [--config-path configFile] [flags]\n\n")
+	fmt.Fprint(os.Stderr, "commands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.usage)
 	}
+	fmt.Fprint(os.Stderr, "\nrun is used when no command is given\n")
+}
 
-	return cfg
+// new flag set of a command, with the shared --config-path flag
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath := fs.String(flagConfigPath, "", "path of the config file (default: config/config.json next to the executable)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: ./btc-relayer %s [flags]\n", name)
+		fs.PrintDefaults()
+	}
+	return fs, configPath
 }
 
 func main() {
+	args := os.Args[1:]
 
-	//init cfg
-	cfg := initCfg()
-
-	//init logger
-	common.InitLogger(&cfg.LogConfig)
-
-	//init executors
-	btcExecutor, err := executor.NewBTCExecutor(cfg)
-	if err != nil {
-		common.Logger.Error(err.Error())
-		return
+	// keep "./btc-relayer" and "./btc-relayer --config-path xx" working as before
+	name := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name = args[0]
+		args = args[1:]
 	}
 
-	coreExecutor, err := executor.NewCOREExecutor(cfg)
-	if err != nil {
-		common.Logger.Error(err.Error())
-		return
+	if name == "help" {
+		printUsage()
+		os.Exit(exitCodeOK)
 	}
 
-	relayerInstance := relayer.NewRelayer(cfg, btcExecutor, coreExecutor)
-
-	common.Logger.Info("Starting relayer")
-	relayerInstance.Start()
+	for _, cmd := range commands {
+		if cmd.name == name {
+			os.Exit(cmd.run(args))
+		}
+	}
 
-	select {}
+	fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", name)
+	printUsage()
+	os.Exit(exitCodeUsage)
 }
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+/**
+open the relay state store of db_config and migrate its tables
+*/
+func OpenDB(cfg *config.DBConfig) (*gorm.DB, error) {
+	db, err := gorm.Open(cfg.GetDialect(), cfg.GetDBPath())
+	if err != nil {
+		return nil, fmt.Errorf("open %s db error, err=%w", cfg.GetDialect(), err)
+	}
+	if err := InitTables(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func InitTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&RelayAttempt{}).Error; err != nil {
+		return fmt.Errorf("migrate tables error, err=%w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// HistoryFilter selects relay attempts, zero fields do not filter
+type HistoryFilter struct {
+	FromHeight int64
+	ToHeight   int64
+	// Since and Until bound the time an attempt was made, Until is exclusive
+	Since   time.Time
+	Until   time.Time
+	Outcome string
+	TxHash  string
+}
+
+/**
+the relay attempts matching filter, ordered by the time they were made
+*/
+func QueryAttempts(db *gorm.DB, filter *HistoryFilter) ([]RelayAttempt, error) {
+	query := db.Model(&RelayAttempt{})
+	if filter.FromHeight > 0 {
+		query = query.Where("height >= ?", filter.FromHeight)
+	}
+	if filter.ToHeight > 0 {
+		query = query.Where("height <= ?", filter.ToHeight)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until.UTC())
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.TxHash != "" {
+		//the hash may be the relay tx or any replacement of it in the comma separated tx_hashes
+		txHash := strings.ToLower(filter.TxHash)
+		query = query.Where("LOWER(tx_hash) = ? OR LOWER(tx_hashes) = ? OR LOWER(tx_hashes) LIKE ? OR LOWER(tx_hashes) LIKE ? OR LOWER(tx_hashes) LIKE ?",
+			txHash, txHash, txHash+",%", "%,"+txHash, "%,"+txHash+",%")
+	}
+
+	var attempts []RelayAttempt
+	if err := query.Order("created_at, id").Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// HistorySummary aggregates relay attempts
+type HistorySummary struct {
+	Attempts int
+	// RelayedByUs and RelayedByCompetitors count headers, not attempts
+	RelayedByUs          int
+	RelayedByCompetitors int
+	// TotalFee is the fee in wei of every included tx, whether it stored the header or not
+	TotalFee *big.Int
+	// AvgLatency is from the btc block time to the Core block including our tx, over the headers relayed by us
+	AvgLatency time.Duration
+}
+
+func Summarize(attempts []RelayAttempt) *HistorySummary {
+	summary := &HistorySummary{Attempts: len(attempts), TotalFee: new(big.Int)}
+	won := make(map[string]bool)
+	lost := make(map[string]bool)
+	var latency time.Duration
+	var latencies int64
+	for _, attempt := range attempts {
+		if fee, ok := new(big.Int).SetString(attempt.FeePaid, 10); ok {
+			summary.TotalFee.Add(summary.TotalFee, fee)
+		}
+		switch {
+		case attempt.Won && !won[attempt.BlockHash]:
+			won[attempt.BlockHash] = true
+			if attempt.IncludedAt != nil {
+				latency += attempt.IncludedAt.Sub(attempt.BlockTime)
+				latencies++
+			}
+		case attempt.Outcome == OutcomeSuperseded:
+			lost[attempt.BlockHash] = true
+		}
+	}
+	//a header we stored after a superseded attempt is ours
+	for blockHash := range lost {
+		if !won[blockHash] {
+			summary.RelayedByCompetitors++
+		}
+	}
+	summary.RelayedByUs = len(won)
+	if latencies > 0 {
+		summary.AvgLatency = latency / time.Duration(latencies)
+	}
+	return summary
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	config "github.com/coredao-org/btc-relayer/config"
+)
+
+func TestQueryAttempts(t *testing.T) {
+	db, err := OpenDB(&config.DBConfig{DBPath: filepath.Join(t.TempDir(), "relay.db")})
+	require.NoError(t, err)
+	defer db.Close()
+
+	day := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	for i, attempt := range []RelayAttempt{
+		{Height: 100, Outcome: "included", TxHash: "0xAA", CreatedAt: day.Add(-time.Hour)},
+		{Height: 101, Outcome: "included", TxHash: "0xbb", CreatedAt: day},
+		{Height: 102, Outcome: OutcomeSuperseded, TxHash: "0xcc", CreatedAt: day.AddDate(0, 1, 0).Add(-time.Second)},
+		{Height: 103, Outcome: OutcomeSimulationFailed, CreatedAt: day.AddDate(0, 1, 0)},
+		//replaced twice, the second replacement was included
+		{Height: 104, Outcome: "included", TxHash: "0xd3", TxHashes: "0xd1,0xD2,0xd3", CreatedAt: day.AddDate(0, 1, 0)},
+		//00:30 in UTC+8 is before day in UTC
+		{Height: 105, Outcome: "included", CreatedAt: day.Add(30 * time.Minute).In(time.FixedZone("UTC+8", 8*60*60))},
+	} {
+		attempt := attempt
+		require.NoError(t, db.Create(&attempt).Error, i)
+	}
+
+	heights := func(filter *HistoryFilter) []int64 {
+		attempts, err := QueryAttempts(db, filter)
+		require.NoError(t, err)
+		heights := make([]int64, 0, len(attempts))
+		for _, attempt := range attempts {
+			heights = append(heights, attempt.Height)
+		}
+		return heights
+	}
+	require.Equal(t, []int64{100, 101, 105, 102, 103, 104}, heights(&HistoryFilter{}))
+	require.Equal(t, []int64{101, 102}, heights(&HistoryFilter{FromHeight: 101, ToHeight: 102}))
+	require.Equal(t, []int64{101, 105, 102}, heights(&HistoryFilter{Since: day, Until: day.AddDate(0, 1, 0)}))
+	require.Equal(t, []int64{100, 101, 105, 104}, heights(&HistoryFilter{Outcome: "included"}))
+	require.Equal(t, []int64{100}, heights(&HistoryFilter{TxHash: "0xaa"}))
+
+	//timestamps compare in UTC whatever the zone they were written or queried in
+	attempts, err := QueryAttempts(db, &HistoryFilter{FromHeight: 105})
+	require.NoError(t, err)
+	require.Equal(t, day.Add(30*time.Minute), attempts[0].CreatedAt.UTC())
+	require.Equal(t, []int64{105}, heights(&HistoryFilter{Since: day.Add(20 * time.Minute), Until: day.Add(40 * time.Minute)}))
+	require.Empty(t, heights(&HistoryFilter{Since: day.Add(8 * time.Hour), Until: day.Add(9 * time.Hour)}))
+	require.Equal(t, []int64{101, 105}, heights(&HistoryFilter{Since: day.In(time.FixedZone("UTC+9", 9*60*60)), Until: day.Add(time.Hour)}))
+
+	//a replaced tx is found by any of its hashes, not by a prefix of one
+	require.Equal(t, []int64{104}, heights(&HistoryFilter{TxHash: "0xd1"}))
+	require.Equal(t, []int64{104}, heights(&HistoryFilter{TxHash: "0xd2"}))
+	require.Equal(t, []int64{104}, heights(&HistoryFilter{TxHash: "0xD3"}))
+	require.Empty(t, heights(&HistoryFilter{TxHash: "0xd"}))
+}
+
+func TestSummarize(t *testing.T) {
+	blockTime := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	included := func(d time.Duration) *time.Time {
+		includedAt := blockTime.Add(d)
+		return &includedAt
+	}
+	summary := Summarize([]RelayAttempt{
+		//out of gas, then stored by the retry
+		{BlockHash: "a", BlockTime: blockTime, Outcome: "reverted", FeePaid: "100"},
+		{BlockHash: "a", BlockTime: blockTime, Outcome: "included", Won: true, FeePaid: "300", IncludedAt: included(time.Minute)},
+		{BlockHash: "b", BlockTime: blockTime, Outcome: "included", Won: true, FeePaid: "300", IncludedAt: included(3 * time.Minute)},
+		//a competitor stored c, our tx was included and rejected
+		{BlockHash: "c", BlockTime: blockTime, Outcome: OutcomeSuperseded, FeePaid: "50", Submitter: "0x01"},
+		{BlockHash: "d", Outcome: OutcomeSuperseded},
+		{BlockHash: "d", Outcome: OutcomeSimulationFailed},
+		{BlockHash: "e", Outcome: OutcomePending},
+	})
+	require.Equal(t, 7, summary.Attempts)
+	require.Equal(t, 2, summary.RelayedByUs)
+	require.Equal(t, 2, summary.RelayedByCompetitors)
+	require.Equal(t, big.NewInt(750), summary.TotalFee)
+	require.Equal(t, 2*time.Minute, summary.AvgLatency)
+
+	summary = Summarize(nil)
+	require.Zero(t, summary.RelayedByUs)
+	require.Zero(t, summary.AvgLatency)
+	require.Equal(t, "0", summary.TotalFee.String())
+}
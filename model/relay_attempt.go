@@ -0,0 +1,82 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// outcomes of a relay attempt besides the executor.TxOutcome strings of a sent tx
+const (
+	// OutcomePending is a sent tx without an outcome yet, a row left pending by a stopped relayer is resumed on start
+	OutcomePending = "pending"
+	// OutcomeSimulationFailed is a relay whose storeBlockHeader simulation failed, nothing was sent
+	OutcomeSimulationFailed = "simulation failed"
+	// OutcomeSendFailed is a relay tx that could not be signed or sent
+	OutcomeSendFailed = "send failed"
+	// OutcomeSuperseded is the executor.TxSuperseded string, another relayer stored the header first
+	OutcomeSuperseded = "superseded"
+	// OutcomeTimedOut is the executor.TxTimedOut string, the tx may be included later so the row is revisited on start
+	OutcomeTimedOut = "timed out"
+)
+
+// RelayAttempt is one try to relay a btc header to Core, fee and gas are set once the tx is included
+type RelayAttempt struct {
+	ID        int64     `json:"id"`
+	Height    int64     `json:"height" gorm:"index"`
+	BlockHash string    `json:"block_hash" gorm:"type:varchar(64);index"`
+	BlockTime time.Time `json:"block_time"`
+	// MirrorSize is the size in bytes of the serialized BtcLightMirrorV2
+	MirrorSize int `json:"mirror_size"`
+	// TxHash is the relay tx, or the replacement of it that was included
+	TxHash string `json:"tx_hash" gorm:"type:varchar(66);index"`
+	// TxHashes are the relay tx and its replacements in the order they were sent, comma separated
+	TxHashes string `json:"tx_hashes" gorm:"type:text"`
+	Nonce    uint64 `json:"nonce"`
+	GasLimit uint64 `json:"gas_limit"`
+	GasUsed  uint64 `json:"gas_used"`
+	// FeePaid is in wei, a decimal string as it may not fit in a bigint column
+	FeePaid string `json:"fee_paid" gorm:"type:varchar(78)"`
+	Outcome string `json:"outcome" gorm:"type:varchar(32);index"`
+	// Won is set if the header was stored by our tx
+	Won bool `json:"won"`
+	// Submitter is who stored the header, for an attempt a competitor won
+	Submitter string `json:"submitter"`
+	Error     string `json:"error" gorm:"type:text"`
+	// IncludedAt is the time of the Core block including the tx
+	IncludedAt *time.Time `json:"included_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (RelayAttempt) TableName() string {
+	return "relay_attempts"
+}
+
+/**
+gorm hook, the timestamps are stored in UTC so created_at compares with the UTC bounds of a history query
+*/
+func (attempt *RelayAttempt) BeforeCreate() error {
+	now := time.Now().UTC()
+	if attempt.CreatedAt.IsZero() {
+		attempt.CreatedAt = now
+	}
+	if attempt.UpdatedAt.IsZero() {
+		attempt.UpdatedAt = now
+	}
+	attempt.CreatedAt = attempt.CreatedAt.UTC()
+	attempt.UpdatedAt = attempt.UpdatedAt.UTC()
+	return nil
+}
+
+/**
+the hashes of the relay tx and its replacements, a row recorded without tx_hashes has its tx_hash only
+*/
+func (attempt *RelayAttempt) SentTxHashes() []string {
+	if attempt.TxHashes == "" {
+		if attempt.TxHash == "" {
+			return nil
+		}
+		return []string{attempt.TxHash}
+	}
+	return strings.Split(attempt.TxHashes, ",")
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+
+	"github.com/coredao-org/btc-relayer/relayer"
+)
+
+var dryRunColumns = []string{"height", "block_hash", "status", "mirror_size", "gas_limit", "return_code", "error"}
+
+func dryRunRange(ctx context.Context, relayerInstance *relayer.Relayer, from int64, to int64) int {
+	report, err := relayerInstance.DryRunRange(ctx, from, to)
+	relayerInstance.Shutdown(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dry run error: %s\n", err.Error())
+		return exitCodeError
+	}
+
+	fmt.Printf("light client tip:     %s (%d blocks past the fork point)\n", report.ChainTip.String(), report.Stale)
+	fmt.Printf("fork point height:    %d\n", report.ForkHeight)
+	fmt.Printf("btc latest height:    %d\n", report.BTCLatestHeight)
+	fmt.Println()
+	if err := writeDryRunTable(os.Stdout, report.Results); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitCodeError
+	}
+
+	//the call data of every simulated header is printed, failed or not
+	code := exitCodeOK
+	for _, result := range report.Results {
+		if result.Simulation != nil {
+			fmt.Printf("\nheight %d would be sent to %s with call data:\n0x%x\n", result.Height, result.Payload.To(), result.Payload.Data())
+		}
+		if result.Status() == relayer.DryRunError || result.Status() == relayer.DryRunFail {
+			code = exitCodeError
+		}
+	}
+	if ctx.Err() != nil {
+		return exitCodeError
+	}
+	return code
+}
+
+// the values of dryRunColumns of a result
+func dryRunRow(result *relayer.DryRunResult) []string {
+	row := []string{strconv.FormatInt(result.Height, 10), "", result.Status(), "", "", "", ""}
+	if result.BlockHash != nil {
+		row[1] = result.BlockHash.String()
+	}
+	if result.Payload != nil {
+		row[3] = strconv.Itoa(result.Payload.MirrorSize())
+	}
+	if simulation := result.Simulation; simulation != nil {
+		row[4] = strconv.FormatUint(simulation.GasLimit, 10)
+		row[5] = strconv.FormatInt(simulation.ReturnCode, 10)
+		if simulation.Err != nil {
+			row[6] = simulation.Err.Error()
+			if simulation.ReturnCode == 0 {
+				row[5] = "reverted"
+			}
+		}
+	}
+	if result.Err != nil {
+		row[6] = result.Err.Error()
+	}
+	return row
+}
+
+func writeDryRunTable(w io.Writer, results []*relayer.DryRunResult) error {
+	rows := [][]string{dryRunColumns}
+	for _, result := range results {
+		rows = append(rows, dryRunRow(result))
+	}
+	return writeTable(w, rows)
+}
+
+var relayColumns = []string{"height", "block_hash", "status", "tx_hash", "error"}
+
+// print the result table of headers relayed by hand, err is what stopped relaying
+func printRelayResults(results []*relayer.HeaderResult, err error) int {
+	rows := [][]string{relayColumns}
+	for _, result := range results {
+		row := []string{strconv.FormatInt(result.Height, 10), "", result.Status, "", ""}
+		if result.BlockHash != nil {
+			row[1] = result.BlockHash.String()
+		}
+		if result.TxHash != (ethcommon.Hash{}) {
+			row[3] = result.TxHash.String()
+		}
+		if result.Status == relayer.HeaderFailed {
+			row[4] = result.Err.Error()
+		}
+		rows = append(rows, row)
+	}
+	if writeErr := writeTable(os.Stdout, rows); writeErr != nil && err == nil {
+		err = writeErr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitCodeError
+	}
+	return exitCodeOK
+}
@@ -1,6 +1,7 @@
 package relayer
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -14,7 +15,7 @@ const (
 	RetryInterval = 5 * time.Second
 )
 
-func (r *Relayer) alert() {
+func (r *Relayer) alert(ctx context.Context) {
 	if !r.cfg.AlertConfig.EnableAlert {
 		return
 	}
@@ -25,7 +26,9 @@ func (r *Relayer) alert() {
 	for {
 		balance, err := r.coreExecutor.GetRelayerBalance()
 		if err != nil {
-			time.Sleep(RetryInterval)
+			if !sleep(ctx, RetryInterval) {
+				return
+			}
 			continue
 		} else {
 			balance, err := decimal.NewFromString(balance.String())
@@ -42,6 +45,18 @@ func (r *Relayer) alert() {
 			}
 		}
 
-		time.Sleep(time.Duration(r.cfg.AlertConfig.Interval) * time.Second)
+		if !sleep(ctx, time.Duration(r.cfg.AlertConfig.Interval)*time.Second) {
+			return
+		}
+	}
+}
+
+/**
+send msg to telegram if alert is enabled
+*/
+func (r *Relayer) sendAlert(msg string) {
+	if !r.cfg.AlertConfig.EnableAlert {
+		return
 	}
+	util.SendTelegramMessage(r.cfg.AlertConfig.Identity, r.cfg.AlertConfig.TelegramBotId, r.cfg.AlertConfig.TelegramChatId, msg)
 }
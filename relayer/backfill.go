@@ -0,0 +1,164 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+
+	"github.com/coredao-org/btc-relayer/common"
+	"github.com/coredao-org/btc-relayer/executor"
+)
+
+// statuses of a header relayed by hand
+const (
+	HeaderSynced  = "synced"
+	HeaderRelayed = "relayed"
+	// HeaderExists is a header relayed by another relayer while our relay tx was pending
+	HeaderExists = "already exists"
+	HeaderFailed = "failed"
+)
+
+// HeaderResult is the outcome of relaying a header by hand
+type HeaderResult struct {
+	Height    int64
+	BlockHash *chainhash.Hash
+	Status    string
+	// TxHash is the relay tx, zero if nothing is sent
+	TxHash ethcommon.Hash
+	Err    error
+}
+
+// backfill relays headers by hand one by one with the relay tx waited for,
+// the blocks are read and the relay txs sent through functions
+type backfill struct {
+	ctx context.Context
+	// fetchHeight and fetchHash return a nil task if the light client has the header already
+	fetchHeight func(height int64) (*chainhash.Hash, *common.Task, error)
+	fetchHash   func(blockHash *chainhash.Hash) (int64, *common.Task, error)
+	relay       func(task *common.Task) (ethcommon.Hash, error)
+}
+
+func (r *Relayer) newBackfill(ctx context.Context) *backfill {
+	return &backfill{
+		ctx: ctx,
+		fetchHeight: func(height int64) (*chainhash.Hash, *common.Task, error) {
+			return r.fetchRelayTask(ctx, height)
+		},
+		fetchHash: func(blockHash *chainhash.Hash) (int64, *common.Task, error) {
+			return r.fetchRelayTaskOfHash(ctx, blockHash)
+		},
+		relay: func(task *common.Task) (ethcommon.Hash, error) {
+			return r.coreExecutor.SyncBTCLightMirror(ctx, task)
+		},
+	}
+}
+
+/**
+get the height of a block and its task, the task is nil if the light client has the block already.
+The block may be off the btc best chain, e.g. a block of a fork to relay by hand
+*/
+func (r *Relayer) fetchRelayTaskOfHash(ctx context.Context, blockHash *chainhash.Hash) (int64, *common.Task, error) {
+	client := r.btcExecutor.GetClient()
+	height, err := client.GetBlockHeight(blockHash)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	relayed, err := r.CheckBlockRelayed(ctx, blockHash)
+	if err != nil {
+		return height, nil, err
+	}
+	if relayed {
+		return height, nil, nil
+	}
+
+	task, err := r.getRelayTask(client, height, blockHash)
+	if err != nil {
+		return height, nil, err
+	}
+	return height, task, nil
+}
+
+/**
+relay the task of result unless the header is synced, fetchErr is the failure to fetch the task
+*/
+func (b *backfill) relayTask(result *HeaderResult, task *common.Task, fetchErr error) *HeaderResult {
+	if fetchErr != nil {
+		result.Status = HeaderFailed
+		result.Err = fetchErr
+		return result
+	}
+	if task == nil {
+		common.Logger.Infof("block is relayed, height:" + executor.Int64ToString(result.Height))
+		result.Status = HeaderSynced
+		return result
+	}
+
+	common.Logger.Infof("start relaying, height:" + executor.Int64ToString(result.Height))
+	result.TxHash, result.Err = b.relay(task)
+	var relayErr *executor.RelayError
+	if errors.As(result.Err, &relayErr) && result.TxHash == (ethcommon.Hash{}) {
+		result.TxHash = relayErr.TxHash
+	}
+	switch {
+	case result.Err == nil:
+		common.Logger.Infof("successfully relayed, height:" + executor.Int64ToString(result.Height))
+		result.Status = HeaderRelayed
+	case errors.Is(result.Err, executor.ErrBlockAlreadyExists):
+		common.Logger.Infof("block already exists, height:" + executor.Int64ToString(result.Height))
+		result.Status = HeaderExists
+	default:
+		result.Status = HeaderFailed
+	}
+	return result
+}
+
+func (b *backfill) relayHeight(height int64) *HeaderResult {
+	blockHash, task, err := b.fetchHeight(height)
+	return b.relayTask(&HeaderResult{Height: height, BlockHash: blockHash}, task, err)
+}
+
+func (b *backfill) relayHash(blockHash *chainhash.Hash) *HeaderResult {
+	height, task, err := b.fetchHash(blockHash)
+	return b.relayTask(&HeaderResult{Height: height, BlockHash: blockHash}, task, err)
+}
+
+/**
+relay the heights in [fromHeight, toHeight] in order, a failed height stops the range as the heights after it need it
+*/
+func (b *backfill) run(fromHeight int64, toHeight int64) ([]*HeaderResult, error) {
+	var results []*HeaderResult
+	for height := fromHeight; height <= toHeight; height++ {
+		if b.ctx.Err() != nil {
+			return results, fmt.Errorf("stopped before height:%d, err=%w", height, b.ctx.Err())
+		}
+		result := b.relayHeight(height)
+		results = append(results, result)
+		if result.Status == HeaderFailed {
+			return results, fmt.Errorf("relay failed, height:%d: %w", height, result.Err)
+		}
+	}
+	return results, nil
+}
+
+/**
+relay the blocks of the btc best chain in [fromHeight, toHeight] one by one until ctx is done,
+skipping the blocks the light client has already. The relay txs go through the nonce manager
+*/
+func (r *Relayer) RelayRange(ctx context.Context, fromHeight int64, toHeight int64) ([]*HeaderResult, error) {
+	return r.newBackfill(ctx).run(fromHeight, toHeight)
+}
+
+/**
+relay the block of blockHash unless the light client has it already, the relay tx is waited for until ctx is done
+*/
+func (r *Relayer) RelayHash(ctx context.Context, blockHash *chainhash.Hash) (*HeaderResult, error) {
+	result := r.newBackfill(ctx).relayHash(blockHash)
+	if result.Status == HeaderFailed {
+		return result, fmt.Errorf("relay failed, blockHash:%s: %w", blockHash.String(), result.Err)
+	}
+	return result, nil
+}
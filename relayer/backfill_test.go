@@ -0,0 +1,116 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coredao-org/btc-relayer/common"
+	"github.com/coredao-org/btc-relayer/executor"
+)
+
+// fakeBackfill has the headers up to synced in the light client, relaying the heights in failAt fails
+type fakeBackfill struct {
+	synced  int64
+	failAt  map[int64]error
+	relayed []int64
+}
+
+// fetch the header at height on branch 0, the task is nil if it is synced
+func (chain *fakeBackfill) fetch(height int64) (*chainhash.Hash, *common.Task, error) {
+	blockHash := branchHash(height, 0)
+	if height <= chain.synced {
+		return &blockHash, nil, nil
+	}
+	return &blockHash, &common.Task{Height: height, BlockHash: &blockHash}, nil
+}
+
+func (chain *fakeBackfill) backfill(ctx context.Context) *backfill {
+	return &backfill{
+		ctx:         ctx,
+		fetchHeight: chain.fetch,
+		fetchHash: func(blockHash *chainhash.Hash) (int64, *common.Task, error) {
+			for height := int64(100); height < 200; height++ {
+				if branchHash(height, 0) == *blockHash {
+					_, task, err := chain.fetch(height)
+					return height, task, err
+				}
+			}
+			return 0, nil, fmt.Errorf("unknown block %s", blockHash.String())
+		},
+		relay: func(task *common.Task) (ethcommon.Hash, error) {
+			chain.relayed = append(chain.relayed, task.Height)
+			if err := chain.failAt[task.Height]; err != nil {
+				return ethcommon.Hash{}, err
+			}
+			return ethcommon.Hash{byte(task.Height)}, nil
+		},
+	}
+}
+
+func statusesOf(results []*HeaderResult) []string {
+	var statuses []string
+	for _, result := range results {
+		statuses = append(statuses, result.Status)
+	}
+	return statuses
+}
+
+func TestBackfill(t *testing.T) {
+	chain := &fakeBackfill{synced: 101, failAt: map[int64]error{103: executor.ErrBlockAlreadyExists}}
+
+	results, err := chain.backfill(context.Background()).run(100, 104)
+	require.NoError(t, err)
+	// synced headers are skipped, a header relayed by another relayer meanwhile is not a failure
+	require.Equal(t, []int64{102, 103, 104}, chain.relayed)
+	require.Equal(t, []string{HeaderSynced, HeaderSynced, HeaderRelayed, HeaderExists, HeaderRelayed}, statusesOf(results))
+	require.Equal(t, ethcommon.Hash{102}, results[2].TxHash)
+	require.Equal(t, ethcommon.Hash{}, results[0].TxHash)
+	require.Equal(t, branchHash(100, 0), *results[0].BlockHash)
+}
+
+func TestBackfill_Failure(t *testing.T) {
+	txHash := ethcommon.Hash{0xaa}
+	chain := &fakeBackfill{synced: 99, failAt: map[int64]error{
+		101: &executor.RelayError{TxHash: txHash, ReturnCode: 10030, Err: executor.ErrNoPrevBlock},
+	}}
+
+	// the heights after a failed one are not relayed
+	results, err := chain.backfill(context.Background()).run(100, 104)
+	require.True(t, errors.Is(err, executor.ErrNoPrevBlock))
+	require.Contains(t, err.Error(), "height:101")
+	require.Equal(t, []string{HeaderRelayed, HeaderFailed}, statusesOf(results))
+	require.Equal(t, txHash, results[1].TxHash)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err = chain.backfill(ctx).run(100, 104)
+	require.True(t, errors.Is(err, context.Canceled))
+	require.Empty(t, results)
+}
+
+func TestBackfill_RelayHash(t *testing.T) {
+	chain := &fakeBackfill{synced: 101}
+	b := chain.backfill(context.Background())
+
+	blockHash := branchHash(101, 0)
+	result := b.relayHash(&blockHash)
+	require.Equal(t, HeaderSynced, result.Status)
+	require.Equal(t, int64(101), result.Height)
+	require.Empty(t, chain.relayed)
+
+	blockHash = branchHash(102, 0)
+	result = b.relayHash(&blockHash)
+	require.Equal(t, HeaderRelayed, result.Status)
+	require.Equal(t, []int64{102}, chain.relayed)
+
+	blockHash = branchHash(102, 1)
+	result = b.relayHash(&blockHash)
+	require.Equal(t, HeaderFailed, result.Status)
+	require.Error(t, result.Err)
+}
@@ -1,8 +1,10 @@
 package relayer
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
@@ -10,10 +12,6 @@ import (
 	"github.com/coredao-org/btc-relayer/executor"
 )
 
-var (
-	ZERO_HASH = chainhash.Hash{00000000000000000000000000000000}
-)
-
 func (r *Relayer) getLatestHeight() uint64 {
 	height, err := r.btcExecutor.GetLatestBlockHeight(r.btcExecutor.GetClient())
 	if err != nil {
@@ -23,209 +21,205 @@ func (r *Relayer) getLatestHeight() uint64 {
 	return uint64(height)
 }
 
-func (r *Relayer) getLastRelayHeight() (int64, error) {
-	//last relayed btc block hash
-	chainTip, err := r.coreExecutor.GetChainTip()
-	if err != nil {
-		return 0, err
-	}
-
-	chainTip = executor.RevertHash(chainTip)
-
-	blockHeaderVerbose, err := r.btcExecutor.GetClient().GetBlockHeaderVerbose(chainTip)
-	if err != nil {
-		return 0, err
-	}
-
-	height := int64(blockHeaderVerbose.Height)
-	blockHash, err := r.btcExecutor.GetClient().GetBlockHash(height)
+/**
+the height of the fork point of the light client chain and the btc best chain, the blocks after it are relayed in order
+*/
+func (r *Relayer) getLastRelayHeight(ctx context.Context) (int64, error) {
+	fork, err := r.newForkResolver(ctx).resolve(r.btcExecutor.HighestHeight)
 	if err != nil {
 		return 0, err
 	}
-	blockHeaderVerboseNew, err := r.btcExecutor.GetClient().GetBlockHeaderVerbose(blockHash)
-
-	//Forked, need to push backwards
-	if chainTip.String() != blockHeaderVerboseNew.Hash {
-		lastHeight, err := r.recursionGetLastHeight(height)
-		if err == nil {
-			height = lastHeight
-		}
+	if fork.Stale > 0 {
+		common.Logger.Infof("light client tip %s is %d blocks past the btc best chain, fork point height:%d, hash:%s",
+			fork.Tip.String(), fork.Stale, fork.Height, fork.BlockHash.String())
 	}
-
-	return height, nil
+	return fork.Height, nil
 }
 
-func (r *Relayer) recursionGetLastHeight(height int64) (int64, error) {
-	for {
-		blockHash, err := r.btcExecutor.GetClient().GetBlockHash(height)
-		if err != nil {
-			return height, err
-		}
-
-		relayed, err := r.coreExecutor.CheckBlockRelayed(blockHash)
-		if err != nil {
-			return height, err
-		}
-
-		if relayed {
-			return height, nil
-		}
-
-		height -= 1
-	}
-}
-
-func (r *Relayer) recursionUnCommitTasks(height int64, lastBlockHash *chainhash.Hash) (*common.TaskSet, error) {
-	var taskSet common.TaskSet
-
-	//get HighestHeight block hash
-	blockHash, err := r.btcExecutor.GetClient().GetBlockHash(r.btcExecutor.HighestHeight)
-	if err != nil {
-		return nil, fmt.Errorf("error")
-	}
-
-	for {
-		if blockHash.IsEqual(lastBlockHash) {
-			break
-		}
-
-		//check if this block is relayed
-		relayed, err := r.CheckBlockRelayed(blockHash)
-		if err != nil {
-			return nil, fmt.Errorf("error")
-		}
-
-		if relayed {
-			break
-		}
-
-		//get block
-		block, err := r.btcExecutor.GetClient().GetBlock(blockHash)
-
-		json, _ := json.Marshal(block)
-		print(json)
-
-		if err != nil {
-			break
-		}
-
-		//new block
-		task := common.Task{
-			Height:    height,
-			BlockHash: blockHash,
-			BLOCK:     block,
-		}
-
-		//append to head
-		taskSet.TaskList = append([]common.Task{task}, taskSet.TaskList...)
-
-		if r.cfg.CrossChainConfig.RecursionHeight > 0 && int64(len(taskSet.TaskList)) >= r.cfg.CrossChainConfig.RecursionHeight {
-			break
-		}
-
-		//get pre block hash
-		blockHash = &block.Header.PrevBlock
-		//Genesis Block
-		if blockHash.IsEqual(&ZERO_HASH) {
-			break
-		}
-
-		height--
-	}
-
-	return &taskSet, nil
-}
-
-func (r *Relayer) RelayerCompetitionDaemon() {
+/**
+relay new blocks until ctx is cancelled. No new height is started after that,
+the relay txs in flight are waited for with r.inFlight
+*/
+func (r *Relayer) RelayerCompetitionDaemon(ctx context.Context) {
 
 	//var err error
 	common.Logger.Info("Start relayer daemon")
+	defer common.Logger.Info("Relayer daemon stopped")
 
-	for {
-		//no new block, sleep
+	for ctx.Err() == nil {
+		//no new block, wait for one
 		if r.btcExecutor.HighestHeight == (int64(0)) {
-			time.Sleep(time.Second)
+			r.btcExecutor.WaitNewTip(ctx, time.Second)
 			continue
 		}
 
-		lastRelayHeight, err := r.getLastRelayHeight()
-
+		lastRelayHeight, err := r.getLastRelayHeight(ctx)
 		if err != nil {
+			common.Logger.Errorf("find last relayed height error, err=%s", err.Error())
+			sleep(ctx, 3*time.Second)
 			continue
 		}
 
-		//no new block, sleep 1s
+		//no new block, wait for a zmq notification or the next poll
 		if lastRelayHeight == r.btcExecutor.HighestHeight {
 			common.Logger.Infof("no new block, current height:" + executor.Int64ToString(lastRelayHeight))
-			time.Sleep(1 * time.Second)
+			r.btcExecutor.WaitNewTip(ctx, time.Duration(r.cfg.BTCConfig.SleepSecond)*time.Second)
 			continue
 		}
 
 		common.Logger.Infof("find last relayed height:" + executor.Int64ToString(lastRelayHeight))
 
-		for i := lastRelayHeight + 1; i <= r.btcExecutor.HighestHeight; {
+		//catch up with several relay txs in flight
+		if window := r.cfg.CrossChainConfig.GetPipelineWindow(); window > 1 && r.btcExecutor.HighestHeight-lastRelayHeight > 1 {
+			relayedHeight, err := r.newRelayPipeline(ctx, window).run(lastRelayHeight+1, r.btcExecutor.HighestHeight)
+			common.Logger.Infof("pipelined relaying to height:" + executor.Int64ToString(relayedHeight))
+			if err != nil {
+				common.Logger.Infof("pipelined relaying failed, err=%s", err.Error())
+				r.alertRejectedHeader(relayedHeight+1, err)
+				sleep(ctx, 3*time.Second)
+			}
+			continue
+		}
+
+		for i := lastRelayHeight + 1; i <= r.btcExecutor.HighestHeight && ctx.Err() == nil; {
 			common.Logger.Infof("start relaying, height:" + executor.Int64ToString(i))
 
-			_, err := r.DoRelayWithHeight(i)
+			_, err := r.DoRelayWithHeight(r.inFlight, i)
 			if err == nil {
 				common.Logger.Infof("successfully relayed, height:" + executor.Int64ToString(i))
 				i++
 				continue
-			} else {
-				time.Sleep(3 * time.Second)
-				common.Logger.Infof("relay failed, height:"+executor.Int64ToString(i), err)
+			}
+
+			//relayed by another relayer meanwhile
+			if errors.Is(err, executor.ErrBlockAlreadyExists) {
+				common.Logger.Infof("block already exists, height:" + executor.Int64ToString(i))
+				i++
+				continue
+			}
+
+			//the light client misses the previous block, find the fork point again. The fork resolver walks
+			//the light client chain back at most recursion_height blocks, the relaying starts after it
+			if errors.Is(err, executor.ErrNoPrevBlock) {
+				common.Logger.Infof("previous block not found, find the fork point again, height:" + executor.Int64ToString(i))
+				sleep(ctx, 3*time.Second)
+				break
+			}
+
+			sleep(ctx, 3*time.Second)
+			common.Logger.Infof("relay failed, height:"+executor.Int64ToString(i), err)
+
+			if r.alertRejectedHeader(i, err) {
+				break
+			}
+
+			//resending would revert again, find the last relayed height again
+			if errors.Is(err, executor.ErrSimulationReverted) {
+				break
 			}
 		}
 	}
 }
 
 /**
-do relay
+alert if the header is invalid for the light client, a node or relayer bug.
+A header failing the local validation was served by a faulty or lying provider, switch to another one
 */
-func (r *Relayer) DoRelayWithHeight(blockHeight int64) (bool, error) {
-	blockHash, err := r.btcExecutor.GetClient().GetBlockHash(blockHeight)
-	if err != nil {
-		return false, err
+func (r *Relayer) alertRejectedHeader(height int64, err error) bool {
+	var headerErr *executor.HeaderError
+	if errors.As(err, &headerErr) {
+		r.sendAlert(fmt.Sprintf("Alert: btc-relayer header at height %d failed local validation: %s", height, err.Error()))
+		r.btcExecutor.SwitchBTClient()
+		return true
+	}
+	if errors.Is(err, executor.ErrProofOfWork) || errors.Is(err, executor.ErrMerkle) {
+		r.sendAlert(fmt.Sprintf("Alert: btc-relayer relay rejected at height %d: %s", height, err.Error()))
+		return true
 	}
+	return false
+}
 
-	//check if this block is relayed
-	relayed, err := r.CheckBlockRelayed(blockHash)
+/**
+do relay, the relay tx is waited for until ctx is done
+*/
+func (r *Relayer) DoRelayWithHeight(ctx context.Context, blockHeight int64) (bool, error) {
+	_, task, err := r.fetchRelayTask(ctx, blockHeight)
 	if err != nil {
 		return false, err
 	}
 
-	if relayed {
+	if task == nil {
 		common.Logger.Infof("block is relayed, height:" + executor.Int64ToString(blockHeight))
 		return true, nil
 	}
 
-	//get block
-	block, err := r.btcExecutor.GetClient().GetBlock(blockHash)
+	err = r.doRelay(ctx, task)
 
-	if err != nil {
-		return false, err
+	return err == nil, err
+}
+
+/**
+get the block hash of height and the client to fetch the block from. With btc_config.quorum above 1
+enough btc endpoints must agree on the block hash, endpoints answering another one are alerted
+*/
+func (r *Relayer) getBlockHash(height int64) (executor.BTCSource, *chainhash.Hash, error) {
+	if r.cfg.BTCConfig.Quorum <= 1 {
+		client := r.btcExecutor.GetClient()
+		blockHash, err := client.GetBlockHash(height)
+		return client, blockHash, err
 	}
 
-	//new block
-	task := common.Task{
-		Height:    blockHeight,
-		BlockHash: blockHash,
-		BLOCK:     block,
+	result, err := r.btcExecutor.GetQuorumBlockHash(height)
+	var quorumErr *executor.QuorumError
+	if errors.As(err, &quorumErr) {
+		if quorumErr.Disagreed() {
+			r.alertQuorum(height, fmt.Sprintf("Alert: btc-relayer %s", err.Error()))
+		}
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if result.Disagreed() {
+		r.alertQuorum(height, fmt.Sprintf("Alert: btc-relayer btc endpoints disagree on the block hash at height %d, relaying %s, %s",
+			height, result.BlockHash.String(), executor.FormatAnswers(result.Answers)))
 	}
+	return result.Client, result.BlockHash, nil
+}
 
-	err = r.doRelay(&task)
+/**
+alert a quorum disagreement once per height, the height is retried until the endpoints agree
+*/
+func (r *Relayer) alertQuorum(height int64, msg string) {
+	common.Logger.Warning(msg)
+	if atomic.SwapInt64(&r.quorumAlertHeight, height) != height {
+		r.sendAlert(msg)
+	}
+}
 
-	return err == nil, err
+/**
+get the task of a block and validate its header locally, an invalid header never reaches the light client
+*/
+func (r *Relayer) getRelayTask(client executor.BTCSource, height int64, blockHash *chainhash.Hash) (*common.Task, error) {
+	task, err := r.btcExecutor.GetRelayTask(client, height, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.btcExecutor.ValidateHeader(client, task); err != nil {
+		return nil, err
+	}
+	return task, nil
 }
-func (r *Relayer) doRelay(task *common.Task) error {
-	_, err := r.coreExecutor.SyncBTCLightMirror(task)
+
+func (r *Relayer) doRelay(ctx context.Context, task *common.Task) error {
+	_, err := r.coreExecutor.SyncBTCLightMirror(ctx, task)
 
 	return err
 }
 
-func (r *Relayer) CheckBlockRelayed(blockHash *chainhash.Hash) (bool, error) {
+func (r *Relayer) CheckBlockRelayed(ctx context.Context, blockHash *chainhash.Hash) (bool, error) {
 	//check if this block if relayed
-	checkResult, err := r.coreExecutor.CheckBlockRelayed(blockHash)
+	checkResult, err := r.coreExecutor.CheckBlockRelayed(ctx, blockHash)
 	if err != nil {
 		return true, fmt.Errorf("error")
 	}
@@ -0,0 +1,184 @@
+package relayer
+
+import (
+	"context"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+
+	"github.com/coredao-org/btc-relayer/common"
+	"github.com/coredao-org/btc-relayer/executor"
+)
+
+// statuses of a header in a dry run
+const (
+	DryRunSynced = "synced"
+	DryRunRelay  = "would relay"
+	DryRunFail   = "would fail"
+	DryRunQueued = "queued"
+	DryRunError  = "error"
+)
+
+// DryRunResult is what relaying the header at Height would send, nothing is signed or sent
+type DryRunResult struct {
+	Height    int64
+	BlockHash *chainhash.Hash
+	// Payload is nil if the light client has the header already
+	Payload *executor.RelayPayload
+	// Simulation is nil if the header is not simulated, only the first header to relay is,
+	// the headers after it depend on it being stored first
+	Simulation *executor.RelaySimulation
+	// Err is a failure to fetch or simulate the header
+	Err error
+}
+
+func (result *DryRunResult) Status() string {
+	switch {
+	case result.Err != nil:
+		return DryRunError
+	case result.Payload == nil:
+		return DryRunSynced
+	case result.Simulation == nil:
+		return DryRunQueued
+	case result.Simulation.Err != nil:
+		return DryRunFail
+	default:
+		return DryRunRelay
+	}
+}
+
+// DryRunReport is the dry run of a height range, with the fork point of the light client chain it starts from
+type DryRunReport struct {
+	ChainTip        *chainhash.Hash
+	ForkHeight      int64
+	Stale           int64
+	BTCLatestHeight int64
+	Results         []*DryRunResult
+}
+
+// dryRunner fetches the heights of a range and simulates the relay tx of the first header to relay
+type dryRunner struct {
+	stop <-chan struct{}
+	// fetch returns a nil payload if the height is relayed already
+	fetch    func(height int64) (*chainhash.Hash, *executor.RelayPayload, error)
+	simulate func(payload *executor.RelayPayload) (*executor.RelaySimulation, error)
+}
+
+func (r *Relayer) newDryRunner(ctx context.Context) *dryRunner {
+	return &dryRunner{
+		stop: ctx.Done(),
+		fetch: func(height int64) (*chainhash.Hash, *executor.RelayPayload, error) {
+			blockHash, task, err := r.fetchRelayTask(ctx, height)
+			if err != nil || task == nil {
+				return blockHash, nil, err
+			}
+			payload, err := executor.BuildRelayPayload(task)
+			return blockHash, payload, err
+		},
+		simulate: func(payload *executor.RelayPayload) (*executor.RelaySimulation, error) {
+			return r.coreExecutor.SimulateRelayPayload(ctx, payload)
+		},
+	}
+}
+
+/**
+dry run the heights in [fromHeight, toHeight] in order, a failure to fetch a height stops the run
+*/
+func (runner *dryRunner) run(fromHeight int64, toHeight int64) []*DryRunResult {
+	var results []*DryRunResult
+	simulated := false
+	for height := fromHeight; height <= toHeight; height++ {
+		select {
+		case <-runner.stop:
+			return results
+		default:
+		}
+
+		result := &DryRunResult{Height: height}
+		results = append(results, result)
+		result.BlockHash, result.Payload, result.Err = runner.fetch(height)
+		if result.Err != nil {
+			return results
+		}
+		if result.Payload == nil || simulated {
+			continue
+		}
+
+		simulated = true
+		result.Simulation, result.Err = runner.simulate(result.Payload)
+	}
+	return results
+}
+
+/**
+dry run the heights in [fromHeight, toHeight]: resolve the fork point of the light client chain, fetch the blocks,
+build their BtcLightMirrorV2 and simulate storeBlockHeader, without signing or sending
+*/
+func (r *Relayer) DryRunRange(ctx context.Context, fromHeight int64, toHeight int64) (*DryRunReport, error) {
+	btcHeight, err := r.btcExecutor.GetLatestBlockHeight(r.btcExecutor.GetClient())
+	if err != nil {
+		return nil, err
+	}
+	fork, err := r.newForkResolver(ctx).resolve(btcHeight)
+	if err != nil {
+		return nil, err
+	}
+	return &DryRunReport{
+		ChainTip:        fork.Tip,
+		ForkHeight:      fork.Height,
+		Stale:           fork.Stale,
+		BTCLatestHeight: btcHeight,
+		Results:         r.newDryRunner(ctx).run(fromHeight, toHeight),
+	}, nil
+}
+
+/**
+dry run the new blocks of every btc tip until ctx is cancelled, the results are logged
+*/
+func (r *Relayer) dryRunDaemon(ctx context.Context) {
+	common.Logger.Info("Start relayer daemon in dry run, nothing is signed or sent")
+	defer common.Logger.Info("Relayer daemon stopped")
+
+	reportedHeight := int64(0)
+	for ctx.Err() == nil {
+		highestHeight := r.btcExecutor.HighestHeight
+		if highestHeight == 0 || highestHeight == reportedHeight {
+			r.btcExecutor.WaitNewTip(ctx, time.Duration(r.cfg.BTCConfig.SleepSecond)*time.Second)
+			continue
+		}
+
+		lastRelayHeight, err := r.getLastRelayHeight(ctx)
+		if err != nil {
+			common.Logger.Errorf("find last relayed height error, err=%s", err.Error())
+			sleep(ctx, 3*time.Second)
+			continue
+		}
+
+		common.Logger.Infof("dry run from height %d to %d", lastRelayHeight+1, highestHeight)
+		for _, result := range r.newDryRunner(ctx).run(lastRelayHeight+1, highestHeight) {
+			logDryRunResult(result)
+		}
+		reportedHeight = highestHeight
+	}
+}
+
+func logDryRunResult(result *DryRunResult) {
+	blockHash := ""
+	if result.BlockHash != nil {
+		blockHash = result.BlockHash.String()
+	}
+	switch status := result.Status(); status {
+	case DryRunError:
+		common.Logger.Errorf("dry run height:%d, blockHash:%s, status:%s, err=%s", result.Height, blockHash, status, result.Err.Error())
+	case DryRunSynced, DryRunQueued:
+		common.Logger.Infof("dry run height:%d, blockHash:%s, status:%s", result.Height, blockHash, status)
+	default:
+		simulation := result.Simulation
+		msg := ""
+		if simulation.Err != nil {
+			msg = ", err=" + simulation.Err.Error()
+		}
+		common.Logger.Infof("dry run height:%d, blockHash:%s, status:%s, to:%s, mirrorSize:%d, gasLimit:%d, returnCode:%d, data:0x%x%s",
+			result.Height, blockHash, status, result.Payload.To(), result.Payload.MirrorSize(), simulation.GasLimit, simulation.ReturnCode, result.Payload.Data(), msg)
+	}
+}
@@ -0,0 +1,68 @@
+package relayer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coredao-org/btc-relayer/common"
+	"github.com/coredao-org/btc-relayer/executor"
+)
+
+func TestDryRunner(t *testing.T) {
+	var simulated []int64
+	runner := &dryRunner{
+		fetch: func(height int64) (*chainhash.Hash, *executor.RelayPayload, error) {
+			blockHash := branchHash(height, 0)
+			if height <= 101 {
+				return &blockHash, nil, nil
+			}
+			if height == 105 {
+				return nil, nil, errors.New("btc node unavailable")
+			}
+			return &blockHash, &executor.RelayPayload{Task: &common.Task{Height: height, BlockHash: &blockHash}}, nil
+		},
+		simulate: func(payload *executor.RelayPayload) (*executor.RelaySimulation, error) {
+			simulated = append(simulated, payload.Task.Height)
+			return &executor.RelaySimulation{Payload: payload, GasLimit: 120000}, nil
+		},
+	}
+
+	results := runner.run(100, 110)
+	// the run stops at the height failing to fetch
+	require.Len(t, results, 6)
+	var statuses []string
+	for _, result := range results {
+		statuses = append(statuses, result.Status())
+	}
+	require.Equal(t, []string{DryRunSynced, DryRunSynced, DryRunRelay, DryRunQueued, DryRunQueued, DryRunError}, statuses)
+	// only the first header to relay is simulated, the others depend on it
+	require.Equal(t, []int64{102}, simulated)
+	require.Equal(t, uint64(120000), results[2].Simulation.GasLimit)
+	require.Equal(t, branchHash(100, 0), *results[0].BlockHash)
+}
+
+func TestDryRunner_Fail(t *testing.T) {
+	runner := &dryRunner{
+		fetch: func(height int64) (*chainhash.Hash, *executor.RelayPayload, error) {
+			blockHash := branchHash(height, 0)
+			return &blockHash, &executor.RelayPayload{Task: &common.Task{Height: height, BlockHash: &blockHash}}, nil
+		},
+		simulate: func(payload *executor.RelayPayload) (*executor.RelaySimulation, error) {
+			return &executor.RelaySimulation{Payload: payload, ReturnCode: 10030, Err: executor.ErrNoPrevBlock}, nil
+		},
+	}
+
+	results := runner.run(100, 101)
+	require.Len(t, results, 2)
+	require.Equal(t, DryRunFail, results[0].Status())
+	require.Equal(t, int64(10030), results[0].Simulation.ReturnCode)
+	require.Equal(t, DryRunQueued, results[1].Status())
+
+	stop := make(chan struct{})
+	close(stop)
+	runner.stop = stop
+	require.Empty(t, runner.run(100, 101))
+}
@@ -0,0 +1,92 @@
+package relayer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+
+	"github.com/coredao-org/btc-relayer/executor"
+)
+
+// forkPoint is the last block of the light client chain that is on the btc best chain,
+// the blocks of the btc best chain after it are the new branch to relay
+type forkPoint struct {
+	Height    int64
+	BlockHash *chainhash.Hash
+	// Tip is the chain tip of the light client, Stale is the number of its blocks after the fork point
+	Tip   *chainhash.Hash
+	Stale int64
+}
+
+// forkResolver finds the fork point by walking the light client chain back from its tip with getPrevHash,
+// the light client and the btc source are read through functions, all hashes are in btc order
+type forkResolver struct {
+	maxDepth  int64
+	chainTip  func() (*chainhash.Hash, error)
+	height    func(blockHash *chainhash.Hash) (int64, error)
+	prevHash  func(blockHash *chainhash.Hash) (*chainhash.Hash, error)
+	blockHash func(height int64) (*chainhash.Hash, error)
+}
+
+func (r *Relayer) newForkResolver(ctx context.Context) *forkResolver {
+	return &forkResolver{
+		maxDepth: r.cfg.CrossChainConfig.RecursionHeight,
+		chainTip: func() (*chainhash.Hash, error) {
+			chainTip, err := r.coreExecutor.GetChainTip(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return executor.RevertHash(chainTip), nil
+		},
+		height: func(blockHash *chainhash.Hash) (int64, error) {
+			return r.coreExecutor.GetHeight(ctx, blockHash)
+		},
+		prevHash: func(blockHash *chainhash.Hash) (*chainhash.Hash, error) {
+			return r.coreExecutor.GetPrevHash(ctx, blockHash)
+		},
+		blockHash: func(height int64) (*chainhash.Hash, error) {
+			_, blockHash, err := r.getBlockHash(height)
+			return blockHash, err
+		},
+	}
+}
+
+/**
+find the fork point of the light client chain and the btc best chain of btcTipHeight.
+The light client blocks above btcTipHeight can not be compared and count as stale
+*/
+func (resolver *forkResolver) resolve(btcTipHeight int64) (*forkPoint, error) {
+	tip, err := resolver.chainTip()
+	if err != nil {
+		return nil, err
+	}
+	height, err := resolver.height(tip)
+	if err != nil {
+		return nil, err
+	}
+
+	blockHash := tip
+	for stale := int64(0); stale <= resolver.maxDepth; stale++ {
+		if height <= btcTipHeight {
+			btcHash, err := resolver.blockHash(height)
+			if err != nil {
+				return nil, err
+			}
+			if btcHash.IsEqual(blockHash) {
+				return &forkPoint{Height: height, BlockHash: blockHash, Tip: tip, Stale: stale}, nil
+			}
+		}
+
+		blockHash, err = resolver.prevHash(blockHash)
+		if err != nil {
+			return nil, err
+		}
+		//walked past the first block of the light client
+		if blockHash.IsEqual(&chainhash.Hash{}) {
+			break
+		}
+		height--
+	}
+	return nil, fmt.Errorf("no common ancestor of light client tip %s and the btc best chain within %d blocks", tip.String(), resolver.maxDepth)
+}
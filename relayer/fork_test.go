@@ -0,0 +1,120 @@
+package relayer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/stretchr/testify/require"
+)
+
+// the hash of a block at height on a branch
+func branchHash(height int64, branch byte) chainhash.Hash {
+	return chainhash.Hash{byte(height), byte(height >> 8), branch}
+}
+
+// forkChains is the chain of the light client and the btc best chain, both start at height 100 on branch 0
+// and switch to their own branch after some height
+type forkChains struct {
+	lcTip       int64
+	lcFork      int64
+	btcTip      int64
+	btcFork     int64
+	firstHeight int64
+}
+
+func (chains *forkChains) lcHash(height int64) chainhash.Hash {
+	if height > chains.lcFork {
+		return branchHash(height, 1)
+	}
+	return branchHash(height, 0)
+}
+
+func (chains *forkChains) btcHash(height int64) chainhash.Hash {
+	if height > chains.btcFork {
+		return branchHash(height, 2)
+	}
+	return branchHash(height, 0)
+}
+
+func (chains *forkChains) resolver(maxDepth int64) *forkResolver {
+	heights := make(map[chainhash.Hash]int64)
+	for height := chains.firstHeight; height <= chains.lcTip; height++ {
+		heights[chains.lcHash(height)] = height
+	}
+	return &forkResolver{
+		maxDepth: maxDepth,
+		chainTip: func() (*chainhash.Hash, error) {
+			tip := chains.lcHash(chains.lcTip)
+			return &tip, nil
+		},
+		height: func(blockHash *chainhash.Hash) (int64, error) {
+			height, ok := heights[*blockHash]
+			if !ok {
+				return 0, fmt.Errorf("unknown block %s", blockHash.String())
+			}
+			return height, nil
+		},
+		prevHash: func(blockHash *chainhash.Hash) (*chainhash.Hash, error) {
+			height, ok := heights[*blockHash]
+			if !ok {
+				return nil, fmt.Errorf("unknown block %s", blockHash.String())
+			}
+			//the light client returns zero for the previous block of its first block
+			prevHash := chainhash.Hash{}
+			if height > chains.firstHeight {
+				prevHash = chains.lcHash(height - 1)
+			}
+			return &prevHash, nil
+		},
+		blockHash: func(height int64) (*chainhash.Hash, error) {
+			if height > chains.btcTip {
+				return nil, fmt.Errorf("no block at height %d", height)
+			}
+			blockHash := chains.btcHash(height)
+			return &blockHash, nil
+		},
+	}
+}
+
+func TestForkResolver(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		chains     forkChains
+		wantHeight int64
+		wantStale  int64
+	}{
+		{"no fork", forkChains{lcTip: 120, lcFork: 1000, btcTip: 125, btcFork: 1000}, 120, 0},
+		{"synced", forkChains{lcTip: 125, lcFork: 1000, btcTip: 125, btcFork: 1000}, 125, 0},
+		{"reorg of 1", forkChains{lcTip: 120, lcFork: 119, btcTip: 121, btcFork: 119}, 119, 1},
+		{"reorg of 3", forkChains{lcTip: 120, lcFork: 117, btcTip: 121, btcFork: 117}, 117, 3},
+		{"reorg of 6, btc best chain not longer yet", forkChains{lcTip: 120, lcFork: 114, btcTip: 120, btcFork: 114}, 114, 6},
+		{"light client ahead of the btc source", forkChains{lcTip: 123, lcFork: 1000, btcTip: 120, btcFork: 1000}, 120, 3},
+		{"reorg below the btc tip of a lagging source", forkChains{lcTip: 123, lcFork: 118, btcTip: 121, btcFork: 118}, 118, 5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.chains.firstHeight = 100
+			fork, err := tc.chains.resolver(10).resolve(tc.chains.btcTip)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantHeight, fork.Height)
+			require.Equal(t, tc.wantStale, fork.Stale)
+			require.Equal(t, tc.chains.btcHash(fork.Height), *fork.BlockHash)
+			require.Equal(t, tc.chains.lcHash(tc.chains.lcTip), *fork.Tip)
+		})
+	}
+
+	t.Run("reorg deeper than the max depth", func(t *testing.T) {
+		chains := forkChains{lcTip: 120, lcFork: 105, btcTip: 122, btcFork: 105, firstHeight: 100}
+		_, err := chains.resolver(10).resolve(chains.btcTip)
+		require.Error(t, err)
+		fork, err := chains.resolver(20).resolve(chains.btcTip)
+		require.NoError(t, err)
+		require.Equal(t, int64(105), fork.Height)
+	})
+
+	t.Run("no common ancestor", func(t *testing.T) {
+		chains := forkChains{lcTip: 103, lcFork: 1000, btcTip: 110, btcFork: 90, firstHeight: 100}
+		_, err := chains.resolver(10).resolve(chains.btcTip)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,207 @@
+package relayer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+
+	"github.com/coredao-org/btc-relayer/common"
+	"github.com/coredao-org/btc-relayer/executor"
+)
+
+// relayPipeline relays consecutive heights with up to window relay txs in flight,
+// blocks are fetched and payloads built ahead concurrently
+type relayPipeline struct {
+	window int
+	// no more height is submitted once stop is closed, the txs in flight are still waited for
+	stop <-chan struct{}
+	// fetch returns nil if the height is relayed already
+	fetch  func(height int64) (*executor.RelayPayload, error)
+	submit func(payload *executor.RelayPayload, simulate bool) (*executor.RelaySubmission, error)
+	wait   func(submission *executor.RelaySubmission) error
+}
+
+/**
+a pipeline stopping when ctx is cancelled, its relay txs are sent and waited for with r.inFlight
+*/
+func (r *Relayer) newRelayPipeline(ctx context.Context, window int64) *relayPipeline {
+	return &relayPipeline{
+		window: int(window),
+		stop:   ctx.Done(),
+		fetch: func(height int64) (*executor.RelayPayload, error) {
+			return r.fetchRelayPayload(ctx, height)
+		},
+		submit: func(payload *executor.RelayPayload, simulate bool) (*executor.RelaySubmission, error) {
+			return r.coreExecutor.SubmitRelayPayload(r.inFlight, payload, simulate)
+		},
+		wait: func(submission *executor.RelaySubmission) error {
+			_, err := r.coreExecutor.WaitBTCLightMirror(r.inFlight, submission)
+			return err
+		},
+	}
+}
+
+func (r *Relayer) fetchRelayPayload(ctx context.Context, height int64) (*executor.RelayPayload, error) {
+	_, task, err := r.fetchRelayTask(ctx, height)
+	if err != nil || task == nil {
+		return nil, err
+	}
+	return executor.BuildRelayPayload(task)
+}
+
+/**
+get the block hash of height and its task, the task is nil if the light client has the block already
+*/
+func (r *Relayer) fetchRelayTask(ctx context.Context, height int64) (*chainhash.Hash, *common.Task, error) {
+	client, blockHash, err := r.getBlockHash(height)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	relayed, err := r.CheckBlockRelayed(ctx, blockHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if relayed {
+		return blockHash, nil, nil
+	}
+
+	task, err := r.getRelayTask(client, height, blockHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	return blockHash, task, nil
+}
+
+type fetchResult struct {
+	height  int64
+	payload *executor.RelayPayload
+	err     error
+}
+
+// pipelineItem is a height waiting for its relay tx, submission is nil if it is relayed already
+type pipelineItem struct {
+	height     int64
+	submission *executor.RelaySubmission
+}
+
+/**
+relay the heights in [fromHeight, toHeight] in order, return the last height relayed.
+A failed height stops the pipeline, the txs already in flight are waited for and
+the heights after the failed one are not counted as relayed
+*/
+func (p *relayPipeline) run(fromHeight int64, toHeight int64) (int64, error) {
+	done := make(chan struct{})
+	defer close(done)
+	results := p.prefetch(done, fromHeight, toHeight)
+
+	lastRelayed := fromHeight - 1
+	//inFlight keeps the heights in order, pending counts the ones with a relay tx
+	var inFlight []*pipelineItem
+	pending := 0
+	var failure error
+	failedHeight := toHeight + 1
+	fail := func(height int64, err error) {
+		if height < failedHeight {
+			failedHeight = height
+			failure = fmt.Errorf("relay failed, height:%d: %w", height, err)
+		}
+	}
+
+	waitOldest := func() {
+		item := inFlight[0]
+		inFlight = inFlight[1:]
+		if item.submission != nil {
+			pending--
+			if err := p.wait(item.submission); err != nil {
+				fail(item.height, err)
+			}
+		}
+		if item.height < failedHeight {
+			lastRelayed = item.height
+			common.Logger.Infof("successfully relayed, height:" + executor.Int64ToString(item.height))
+		} else if item.submission != nil {
+			common.Logger.Infof("relay tx after the failed height settled, height:" + executor.Int64ToString(item.height))
+		}
+	}
+
+	for result := range results {
+		if p.stopping() {
+			common.Logger.Infof("pipeline stopped, wait for %d relay txs in flight", len(inFlight))
+			break
+		}
+		fetched := <-result
+		if fetched.err != nil {
+			fail(fetched.height, fetched.err)
+			break
+		}
+		if fetched.payload == nil {
+			common.Logger.Infof("block is relayed, height:" + executor.Int64ToString(fetched.height))
+			//it counts as relayed once the txs before it are
+			if len(inFlight) == 0 {
+				lastRelayed = fetched.height
+			} else {
+				inFlight = append(inFlight, &pipelineItem{height: fetched.height})
+			}
+			continue
+		}
+
+		for pending >= p.window && failure == nil {
+			waitOldest()
+		}
+		//the relayed heights left at the front have no tx to wait for
+		for len(inFlight) > 0 && inFlight[0].submission == nil && failure == nil {
+			waitOldest()
+		}
+		if failure != nil {
+			break
+		}
+
+		//only the first header in flight can be simulated, the others depend on pending headers
+		submission, err := p.submit(fetched.payload, pending == 0)
+		if err != nil {
+			fail(fetched.height, err)
+			break
+		}
+		inFlight = append(inFlight, &pipelineItem{height: fetched.height, submission: submission})
+		pending++
+	}
+
+	for len(inFlight) > 0 {
+		waitOldest()
+	}
+	return lastRelayed, failure
+}
+
+func (p *relayPipeline) stopping() bool {
+	select {
+	case <-p.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+/**
+fetch the heights concurrently, the results are delivered in height order, at most 2*window ahead
+*/
+func (p *relayPipeline) prefetch(done <-chan struct{}, fromHeight int64, toHeight int64) <-chan chan fetchResult {
+	ordered := make(chan chan fetchResult, 2*p.window)
+	go func() {
+		defer close(ordered)
+		for height := fromHeight; height <= toHeight; height++ {
+			result := make(chan fetchResult, 1)
+			select {
+			case ordered <- result:
+			case <-done:
+				return
+			}
+			go func(height int64) {
+				payload, err := p.fetch(height)
+				result <- fetchResult{height: height, payload: payload, err: err}
+			}(height)
+		}
+	}()
+	return ordered
+}
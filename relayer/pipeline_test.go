@@ -0,0 +1,136 @@
+package relayer
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coredao-org/btc-relayer/common"
+	"github.com/coredao-org/btc-relayer/executor"
+)
+
+// fakeChain records what the pipeline submits and fails the heights in failAt
+type fakeChain struct {
+	mu          sync.Mutex
+	window      int
+	relayed     map[int64]bool
+	failAt      map[int64]error
+	fetchErrAt  int64
+	submitted   []int64
+	simulated   []int64
+	inFlight    int
+	maxInFlight int
+	// inFlightAt is the number of txs in flight when a height was submitted
+	inFlightAt map[int64]int
+}
+
+func (chain *fakeChain) pipeline() *relayPipeline {
+	return &relayPipeline{
+		window: chain.window,
+		fetch: func(height int64) (*executor.RelayPayload, error) {
+			if height == chain.fetchErrAt {
+				return nil, errors.New("btc node unavailable")
+			}
+			if chain.relayed[height] {
+				return nil, nil
+			}
+			return &executor.RelayPayload{Task: &common.Task{Height: height}}, nil
+		},
+		submit: func(payload *executor.RelayPayload, simulate bool) (*executor.RelaySubmission, error) {
+			chain.mu.Lock()
+			defer chain.mu.Unlock()
+			chain.submitted = append(chain.submitted, payload.Task.Height)
+			if chain.inFlightAt != nil {
+				chain.inFlightAt[payload.Task.Height] = chain.inFlight
+			}
+			if simulate {
+				chain.simulated = append(chain.simulated, payload.Task.Height)
+			}
+			chain.inFlight++
+			if chain.inFlight > chain.maxInFlight {
+				chain.maxInFlight = chain.inFlight
+			}
+			return &executor.RelaySubmission{Task: payload.Task}, nil
+		},
+		wait: func(submission *executor.RelaySubmission) error {
+			chain.mu.Lock()
+			defer chain.mu.Unlock()
+			chain.inFlight--
+			return chain.failAt[submission.Task.Height]
+		},
+	}
+}
+
+func TestRelayPipeline(t *testing.T) {
+	chain := &fakeChain{window: 3, relayed: map[int64]bool{4: true}}
+
+	lastRelayed, err := chain.pipeline().run(1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), lastRelayed)
+	require.Equal(t, []int64{1, 2, 3, 5, 6, 7, 8, 9, 10}, chain.submitted)
+	require.LessOrEqual(t, chain.maxInFlight, 3)
+	require.Equal(t, 0, chain.inFlight)
+	// only a header with nothing pending before it is simulated
+	require.Equal(t, int64(1), chain.simulated[0])
+}
+
+func TestRelayPipeline_RelayedHeights(t *testing.T) {
+	chain := &fakeChain{window: 2, relayed: map[int64]bool{1: true, 3: true, 4: true, 5: true, 8: true}, inFlightAt: make(map[int64]int)}
+
+	lastRelayed, err := chain.pipeline().run(1, 8)
+	require.NoError(t, err)
+	require.Equal(t, int64(8), lastRelayed)
+	require.Equal(t, []int64{2, 6, 7}, chain.submitted)
+	// a header after a relayed one with no tx pending is simulated
+	require.Equal(t, []int64{2}, chain.simulated)
+	// the relayed heights do not take a place in the window
+	require.Equal(t, 1, chain.inFlightAt[6])
+	require.Equal(t, 1, chain.inFlightAt[7])
+	require.LessOrEqual(t, chain.maxInFlight, 2)
+	require.Equal(t, 0, chain.inFlight)
+}
+
+func TestRelayPipeline_Failure(t *testing.T) {
+	chain := &fakeChain{window: 3, failAt: map[int64]error{5: executor.ErrNoPrevBlock, 6: executor.ErrNoPrevBlock}}
+
+	lastRelayed, err := chain.pipeline().run(1, 20)
+	require.True(t, errors.Is(err, executor.ErrNoPrevBlock))
+	require.Contains(t, err.Error(), "height:5")
+	require.Equal(t, int64(4), lastRelayed)
+	// nothing is sent after the failure, the txs in flight are settled
+	require.LessOrEqual(t, chain.submitted[len(chain.submitted)-1], int64(5+chain.window-1))
+	require.Equal(t, 0, chain.inFlight)
+}
+
+func TestRelayPipeline_FetchError(t *testing.T) {
+	chain := &fakeChain{window: 4, fetchErrAt: 7}
+
+	lastRelayed, err := chain.pipeline().run(1, 20)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "height:7")
+	require.Equal(t, int64(6), lastRelayed)
+	require.Equal(t, []int64{1, 2, 3, 4, 5, 6}, chain.submitted)
+}
+
+func TestRelayPipeline_Stop(t *testing.T) {
+	chain := &fakeChain{window: 3}
+	stop := make(chan struct{})
+	p := chain.pipeline()
+	p.stop = stop
+	submit := p.submit
+	p.submit = func(payload *executor.RelayPayload, simulate bool) (*executor.RelaySubmission, error) {
+		//stop while the third header is in flight
+		if payload.Task.Height == 3 {
+			close(stop)
+		}
+		return submit(payload, simulate)
+	}
+
+	lastRelayed, err := p.run(1, 20)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), lastRelayed)
+	require.Equal(t, []int64{1, 2, 3}, chain.submitted)
+	require.Equal(t, 0, chain.inFlight)
+}
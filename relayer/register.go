@@ -1,34 +1,82 @@
 package relayer
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/coredao-org/btc-relayer/common"
 )
 
-func (r *Relayer) registerRelayerHub() {
+const (
+	RegisterConfirmWait = 20 * time.Second
+)
+
+func (r *Relayer) RegisterRelayerHub() error {
 	isRelayer, err := r.coreExecutor.IsRelayer()
 	if err != nil {
-		panic(err)
+		return err
 	}
 	if isRelayer {
 		common.Logger.Info("This relayer has already been registered")
-		return
+		return nil
 	}
 
 	common.Logger.Info("Register this relayer to RelayerHub")
 	_, err = r.coreExecutor.RegisterRelayer()
 	if err != nil {
-		panic(err)
+		return err
 	}
 	common.Logger.Info("Waiting for registration tx to be confirmed")
-	time.Sleep(20 * time.Second)
+	time.Sleep(RegisterConfirmWait)
 
 	isRelayer, err = r.coreExecutor.IsRelayer()
 	if err != nil {
-		panic(err)
+		return err
+	}
+	if !isRelayer {
+		return fmt.Errorf("failed to register relayer")
+	}
+	return nil
+}
+
+func (r *Relayer) UnregisterRelayerHub() error {
+	isRelayer, err := r.coreExecutor.IsRelayer()
+	if err != nil {
+		return err
+	}
+	if !isRelayer {
+		common.Logger.Info("This relayer is not registered")
+		return nil
+	}
+
+	common.Logger.Info("Unregister this relayer from RelayerHub")
+	_, err = r.coreExecutor.UnregisterRelayer()
+	if err != nil {
+		return err
+	}
+	common.Logger.Info("Waiting for unregistration tx to be confirmed")
+	time.Sleep(RegisterConfirmWait)
+
+	isRelayer, err = r.coreExecutor.IsRelayer()
+	if err != nil {
+		return err
+	}
+	if isRelayer {
+		return fmt.Errorf("failed to unregister relayer")
+	}
+	return nil
+}
+
+/**
+warn if the relayer is not registered, the light client may reject its relay txs. Nothing is sent
+*/
+func (r *Relayer) checkRegistered() {
+	isRelayer, err := r.coreExecutor.IsRelayer()
+	if err != nil {
+		common.Logger.Warningf("query relayer registration error, err=%s", err.Error())
+		return
 	}
 	if !isRelayer {
-		panic("Failed to register relayer")
+		common.Logger.Warningf("relayer %s is not registered, run without --dry-run registers it", r.coreExecutor.GetRelayerAddress().String())
 	}
 }
@@ -1,33 +1,146 @@
 package relayer
 
 import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coredao-org/btc-relayer/common"
 	config "github.com/coredao-org/btc-relayer/config"
 	"github.com/coredao-org/btc-relayer/executor"
 )
 
+const (
+	// how long the relay loop is given to return after the relay txs in flight are cut at the shutdown deadline
+	ShutdownGrace = time.Second
+)
+
 type Relayer struct {
 	cfg          *config.Config
 	btcExecutor  *executor.BTCExecutor
 	coreExecutor *executor.COREExecutor
+	// quorumAlertHeight is the last height a quorum disagreement was alerted for
+	quorumAlertHeight int64
+	// dryRun simulates the relay txs without signing or sending them
+	dryRun bool
+
+	// wg tracks the goroutines of Start. inFlight is the context of the relay txs sent, it outlives
+	// the context of Start so a tx in flight is waited for, and is cancelled at the shutdown deadline
+	wg             sync.WaitGroup
+	inFlight       context.Context
+	cancelInFlight context.CancelFunc
 }
 
 func NewRelayer(cfg *config.Config, BTCExecutor *executor.BTCExecutor, coreExecutor *executor.COREExecutor) *Relayer {
+	inFlight, cancelInFlight := context.WithCancel(context.Background())
 	return &Relayer{
-		cfg:          cfg,
-		btcExecutor:  BTCExecutor,
-		coreExecutor: coreExecutor,
+		cfg:            cfg,
+		btcExecutor:    BTCExecutor,
+		coreExecutor:   coreExecutor,
+		inFlight:       inFlight,
+		cancelInFlight: cancelInFlight,
 	}
 }
 
-func (r *Relayer) Start() {
+/**
+simulate the relay txs without signing or sending them, the relayer is not registered either
+*/
+func (r *Relayer) SetDryRun(dryRun bool) {
+	r.dryRun = dryRun
+}
+
+/**
+start relaying until ctx is cancelled, then call Shutdown to wait for the relay txs in flight.
+In a dry run the new blocks are only simulated and logged
+*/
+func (r *Relayer) Start(ctx context.Context) error {
+	if r.dryRun {
+		r.checkRegistered()
+		r.startLoops(ctx, func() { r.dryRunDaemon(ctx) })
+		return nil
+	}
 
 	//register relayer
-	r.registerRelayerHub()
+	if err := r.RegisterRelayerHub(); err != nil {
+		return err
+	}
+
+	//settle the relay txs left pending by the last run before relaying again
+	if err := r.coreExecutor.ResumePendingRelays(ctx); err != nil {
+		return err
+	}
 
-	go r.RelayerCompetitionDaemon()
+	r.startLoops(ctx, func() { r.RelayerCompetitionDaemon(ctx) })
+	return nil
+}
+
+func (r *Relayer) startLoops(ctx context.Context, daemon func()) {
+	r.goRun(daemon)
 
-	go r.btcExecutor.UpdateClients()
-	go r.coreExecutor.UpdateClients()
+	r.goRun(func() { r.btcExecutor.UpdateClients(ctx) })
+	r.btcExecutor.SubscribeBlocks(ctx, &r.wg)
+	r.goRun(func() { r.coreExecutor.UpdateClients(ctx) })
 
-	go r.alert()
+	r.goRun(func() { r.alert(ctx) })
+}
+
+func (r *Relayer) goRun(run func()) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		run()
+	}()
+}
+
+/**
+wait for the goroutines of Start to return after its context is cancelled. A relay tx in flight is waited for
+until ctx is done, then it is left pending in the relay state store and resumed on the next start.
+The btc p2p peers are disconnected, and the relay state store is closed last
+*/
+func (r *Relayer) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(stopped)
+	}()
+
+	var err error
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		r.cancelInFlight()
+		err = fmt.Errorf("shutdown deadline passed with relay txs in flight: %w", ctx.Err())
+		select {
+		case <-stopped:
+		case <-time.After(ShutdownGrace):
+		}
+	}
+	r.cancelInFlight()
+	r.btcExecutor.Close()
+
+	if closeErr := r.coreExecutor.Close(); closeErr != nil {
+		common.Logger.Errorf("close relay state store error, err=%s", closeErr.Error())
+		if err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// ShutdownTimeout is how long Shutdown may wait, cross_chain_config.shutdown_timeout_second
+func (r *Relayer) ShutdownTimeout() time.Duration {
+	return r.cfg.CrossChainConfig.GetShutdownTimeout()
+}
+
+/**
+sleep for d unless ctx is done first, return whether the whole d passed
+*/
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
 }
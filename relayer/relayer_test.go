@@ -0,0 +1,48 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	config "github.com/coredao-org/btc-relayer/config"
+	"github.com/coredao-org/btc-relayer/executor"
+)
+
+func TestRelayer_Shutdown(t *testing.T) {
+	r := NewRelayer(&config.Config{}, &executor.BTCExecutor{}, &executor.COREExecutor{})
+	ctx, stop := context.WithCancel(context.Background())
+	//a loop stopping on ctx and a relay tx in flight settled soon after
+	r.goRun(func() { <-ctx.Done() })
+	r.goRun(func() {
+		<-ctx.Done()
+		sleep(r.inFlight, 50*time.Millisecond)
+	})
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, r.Shutdown(shutdownCtx))
+	require.Error(t, r.inFlight.Err())
+}
+
+func TestRelayer_ShutdownDeadline(t *testing.T) {
+	r := NewRelayer(&config.Config{}, &executor.BTCExecutor{}, &executor.COREExecutor{})
+	//a relay tx in flight without an outcome before the deadline
+	cut := make(chan struct{})
+	r.goRun(func() {
+		<-r.inFlight.Done()
+		close(cut)
+	})
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := r.Shutdown(shutdownCtx)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.Less(t, time.Since(start), ShutdownGrace)
+	<-cut
+}
@@ -0,0 +1,57 @@
+package relayer
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/coredao-org/btc-relayer/executor"
+)
+
+type Status struct {
+	RelayerAddress  string
+	Registered      bool
+	Balance         *big.Int
+	ChainTip        string
+	ChainTipHeight  int64
+	BTCLatestHeight int64
+}
+
+// collect the relayer account and light client status
+func (r *Relayer) GetStatus() (*Status, error) {
+	status := &Status{
+		RelayerAddress: r.coreExecutor.GetRelayerAddress().String(),
+	}
+
+	isRelayer, err := r.coreExecutor.IsRelayer()
+	if err != nil {
+		return nil, err
+	}
+	status.Registered = isRelayer
+
+	balance, err := r.coreExecutor.GetRelayerBalance()
+	if err != nil {
+		return nil, err
+	}
+	status.Balance = balance
+
+	chainTip, err := r.coreExecutor.GetChainTip(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	chainTip = executor.RevertHash(chainTip)
+	status.ChainTip = chainTip.String()
+
+	chainTipHeight, err := r.coreExecutor.GetHeight(context.Background(), chainTip)
+	if err != nil {
+		return nil, err
+	}
+	status.ChainTipHeight = chainTipHeight
+
+	height, err := r.btcExecutor.GetLatestBlockHeight(r.btcExecutor.GetClient())
+	if err != nil {
+		return nil, err
+	}
+	status.BTCLatestHeight = height
+
+	return status, nil
+}